@@ -13,6 +13,11 @@ type PluginSettings struct {
 	CacheTime time.Duration         `json:"cacheTime"`
 	Secrets   *SecretPluginSettings `json:"-"`
 	Timezone  string                `json:"timeZone"`
+	// Use the UTC offset of the PRTG server clock if it does not match the configured timezone
+	AutoTimezone bool `json:"autoTimezone"`
+	// Decimal separator of formatted values ("," or "."), depends on the language of the PRTG server.
+	// Empty guesses it per value.
+	DecimalSeparator string `json:"decimalSeparator"`
 }
 
 type SecretPluginSettings struct {
@@ -45,6 +50,12 @@ func LoadPluginSettings(source backend.DataSourceInstanceSettings) (*PluginSetti
 		settings.Timezone = "UTC"
 	}
 
+	if settings.DecimalSeparator != "" && settings.DecimalSeparator != "," && settings.DecimalSeparator != "." {
+		backend.Logger.Warn("Invalid decimal separator in settings, detecting it per value",
+			"decimalSeparator", settings.DecimalSeparator)
+		settings.DecimalSeparator = ""
+	}
+
 	settings.Secrets = loadSecretPluginSettings(source.DecryptedSecureJSONData)
 
 	return &settings, nil
@@ -0,0 +1,281 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// adhocFilter is one Grafana ad-hoc filter, e.g. status = Down or tags =~ core
+type adhocFilter struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+// adhocFilterKeys lists the object columns ad-hoc filters can refer to
+var adhocFilterKeys = []string{"probe", "group", "device", "sensor", "status", "tags", "type", "priority"}
+
+// adhocMatcher is a validated ad-hoc filter
+type adhocMatcher struct {
+	adhocFilter
+	re     *regexp.Regexp
+	number float64
+}
+
+/* =================================== FILTER COMPILATION ====================================== */
+
+// newAdhocMatchers validates the ad-hoc filters of a query and compiles regular expressions
+func newAdhocMatchers(filters []adhocFilter) ([]adhocMatcher, error) {
+	return newFilterMatchers(filters, isAdhocFilterKey)
+}
+
+// newFilterMatchers validates filters on the columns accepted by validKey
+func newFilterMatchers(filters []adhocFilter, validKey func(string) bool) ([]adhocMatcher, error) {
+	matchers := make([]adhocMatcher, 0, len(filters))
+	for _, f := range filters {
+		if !validKey(f.Key) {
+			return nil, fmt.Errorf("unknown filter key '%s'", f.Key)
+		}
+		m := adhocMatcher{adhocFilter: f}
+		switch f.Operator {
+		case "=", "!=":
+		case "=~", "!~":
+			re, err := regexp.Compile("(?i)" + f.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter regex for '%s': %w", f.Key, err)
+			}
+			m.re = re
+		case "<", ">":
+			number, err := strconv.ParseFloat(f.Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("filter '%s %s' requires a number", f.Key, f.Operator)
+			}
+			m.number = number
+		default:
+			return nil, fmt.Errorf("unsupported filter operator '%s'", f.Operator)
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+func isAdhocFilterKey(key string) bool {
+	for _, k := range adhocFilterKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// applyAdhocParams moves the filters PRTG can evaluate into filter_* parameters of a table.json
+// request and returns the ones left for matchesAdhoc. Filters on columns the table does not
+// have are returned as ignored, e.g. a sensor filter on the group table.
+func applyAdhocParams(matchers []adhocMatcher, columns string, params map[string]string) (remaining, ignored []adhocMatcher) {
+	applicable, ignored := splitAdhocFilters(matchers, columns)
+	remaining = make([]adhocMatcher, 0, len(applicable))
+	for _, m := range applicable {
+		param, value := m.prtgFilter()
+		if _, used := params[param]; param == "" || used {
+			remaining = append(remaining, m)
+			continue
+		}
+		params[param] = value
+	}
+	return remaining, ignored
+}
+
+// splitAdhocFilters separates the filters on the comma separated columns from the filters on
+// columns the objects do not have
+func splitAdhocFilters(matchers []adhocMatcher, columns string) (applicable, ignored []adhocMatcher) {
+	available := make(map[string]struct{})
+	for _, column := range strings.Split(columns, ",") {
+		available[column] = struct{}{}
+	}
+	for _, m := range matchers {
+		if _, ok := available[m.Key]; ok {
+			applicable = append(applicable, m)
+		} else {
+			ignored = append(ignored, m)
+		}
+	}
+	return applicable, ignored
+}
+
+// ignoredAdhocNotice tells the user which ad-hoc filters did not apply to the query
+func ignoredAdhocNotice(ignored []adhocMatcher, reason string) data.Notice {
+	filters := make([]string, len(ignored))
+	for i, m := range ignored {
+		filters[i] = fmt.Sprintf("%s %s %s", m.Key, m.Operator, m.Value)
+	}
+	return data.Notice{
+		Severity: data.NoticeSeverityInfo,
+		Text:     fmt.Sprintf("Ad-hoc filters ignored, %s: %s", reason, strings.Join(filters, ", ")),
+	}
+}
+
+// prtgFilter returns the table.json parameter of an equality filter, empty if PRTG cannot evaluate it
+func (m adhocMatcher) prtgFilter() (string, string) {
+	if m.Operator != "=" || m.Value == "" {
+		return "", ""
+	}
+	switch m.Key {
+	case "probe", "group", "device", "sensor":
+		return "filter_" + m.Key, m.Value
+	case "tags":
+		return "filter_tags", fmt.Sprintf("@tag(%s)", m.Value)
+	case "status":
+		// Names with several codes, e.g. paused, are matched on the rows
+		if codes, ok := statusCodes(m.Value); ok && len(codes) == 1 {
+			return "filter_status", codes[0]
+		}
+	}
+	return "", ""
+}
+
+/* =================================== IN-MEMORY MATCHING ====================================== */
+
+// matchesAdhoc reports whether a table.json row passes all filters
+func matchesAdhoc(matchers []adhocMatcher, row map[string]interface{}) bool {
+	for _, m := range matchers {
+		if !m.matches(row) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m adhocMatcher) matches(row map[string]interface{}) bool {
+	value := rowString(row, m.Key)
+	raw := rowString(row, m.Key+"_raw")
+
+	switch m.Operator {
+	case "=", "!=":
+		equal := strings.EqualFold(value, m.Value) || (raw != "" && strings.EqualFold(raw, m.Value))
+		switch m.Key {
+		case "tags":
+			equal = hasAllTags(value, []string{strings.ToLower(m.Value)})
+		case "status":
+			if codes, ok := statusFilterCodes[strings.ToLower(m.Value)]; ok && !equal {
+				equal = hasStatus(row, codes)
+			}
+		}
+		return equal == (m.Operator == "=")
+	case "=~", "!~":
+		return m.re.MatchString(value) == (m.Operator == "=~")
+	case "<", ">":
+		if raw == "" {
+			raw = value
+		}
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return false
+		}
+		if m.Operator == "<" {
+			return number < m.number
+		}
+		return number > m.number
+	}
+	return false
+}
+
+// sensorRow returns the filterable columns of a sensor as table.json row
+func sensorRow(sensor PrtgSensorListItemStruct) map[string]interface{} {
+	return map[string]interface{}{
+		"probe":        sensor.Probe,
+		"group":        sensor.Group,
+		"device":       sensor.Device,
+		"sensor":       sensor.Sensor,
+		"status":       sensor.Status,
+		"status_raw":   float64(sensor.StatusRAW),
+		"tags":         sensor.Tags,
+		"type":         sensor.Type,
+		"type_raw":     sensor.TypeRAW,
+		"priority":     sensor.Priority,
+		"priority_raw": float64(sensor.PriorityRAW),
+	}
+}
+
+// groupRow returns the filterable columns of a group as table.json row
+func groupRow(g PrtgGroupListItemStruct) map[string]interface{} {
+	return map[string]interface{}{
+		"group":        g.Group,
+		"status":       g.Status,
+		"status_raw":   float64(g.StatusRAW),
+		"tags":         g.Tags,
+		"priority":     g.Priority,
+		"priority_raw": float64(g.PriorityRAW),
+	}
+}
+
+// deviceRow returns the filterable columns of a device as table.json row
+func deviceRow(dev PrtgDeviceListItemStruct) map[string]interface{} {
+	return map[string]interface{}{
+		"group":        dev.Group,
+		"device":       dev.Device,
+		"status":       dev.Status,
+		"status_raw":   float64(dev.StatusRAW),
+		"tags":         dev.Tags,
+		"priority":     dev.Priority,
+		"priority_raw": float64(dev.PriorityRAW),
+	}
+}
+
+// propertyAdhocColumns are the columns ad-hoc filters apply to in property queries
+var propertyAdhocColumns = map[string]string{
+	"group":  "group,status,tags,priority",
+	"device": "group,device,status,tags,priority",
+	"sensor": sensorTableColumns,
+}
+
+// filterSensors keeps the sensors passing the ad-hoc filters of the query
+func filterSensors(matchers []adhocMatcher, sensors []PrtgSensorListItemStruct) []PrtgSensorListItemStruct {
+	if len(matchers) == 0 {
+		return sensors
+	}
+	result := make([]PrtgSensorListItemStruct, 0, len(sensors))
+	for _, sensor := range sensors {
+		if matchesAdhoc(matchers, sensorRow(sensor)) {
+			result = append(result, sensor)
+		}
+	}
+	return result
+}
+
+/* =================================== TAG KEYS AND VALUES ===================================== */
+
+// adhocTagValues returns the distinct values of a key over all sensors, tags are split
+func (d *Datasource) adhocTagValues(key string) ([]string, error) {
+	if !isAdhocFilterKey(key) {
+		return nil, fmt.Errorf("unknown ad-hoc filter key '%s'", key)
+	}
+	rows, err := d.api.GetTable("sensors", map[string]string{"columns": "objid," + key})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{})
+	values := make([]string, 0)
+	add := func(value string) {
+		if _, ok := seen[value]; value != "" && !ok {
+			seen[value] = struct{}{}
+			values = append(values, value)
+		}
+	}
+	for _, row := range rows {
+		if key == "tags" {
+			for _, tag := range strings.FieldsFunc(rowString(row, key), func(r rune) bool { return r == ' ' || r == ',' }) {
+				add(tag)
+			}
+			continue
+		}
+		add(rowString(row, key))
+	}
+	sort.Strings(values)
+	return values, nil
+}
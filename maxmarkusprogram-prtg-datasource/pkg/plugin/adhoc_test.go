@@ -0,0 +1,192 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func hasNotice(frame *data.Frame, text string) bool {
+	if frame.Meta == nil {
+		return false
+	}
+	for _, notice := range frame.Meta.Notices {
+		if strings.Contains(notice.Text, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAdhocFiltersOnSingleSensor(t *testing.T) {
+	ds := newTestDatasource(t, alertTestAPI(), nil)
+	timeRange := backend.TimeRange{From: alertEnd.Add(-time.Hour), To: alertEnd}
+	qm := queryModel{QueryType: "metrics", SensorId: "1001", ChannelIds: []string{"0"}}
+
+	qm.AdhocFilters = []adhocFilter{{Key: "device", Operator: "=", Value: "web01"}}
+	res := ds.handleMetricsQuery(context.Background(), qm, timeRange, "A")
+	if res.Error != nil || len(res.Frames) != 1 || len(res.Frames[0].Fields) != 2 {
+		t.Fatalf("matching sensor: error %v, frames %v", res.Error, res.Frames)
+	}
+
+	qm.AdhocFilters = []adhocFilter{{Key: "device", Operator: "=", Value: "dc01"}}
+	res = ds.handleMetricsQuery(context.Background(), qm, timeRange, "A")
+	if res.Error != nil || len(res.Frames) != 1 {
+		t.Fatalf("filtered sensor: error %v, frames %v", res.Error, res.Frames)
+	}
+	if frame := res.Frames[0]; len(frame.Fields) != 0 || !hasNotice(frame, "does not match the ad-hoc filters") {
+		t.Errorf("filtered sensor returned %d fields, notices %v", len(frame.Fields), frame.Meta)
+	}
+}
+
+func TestAdhocFiltersOnPropertyQuery(t *testing.T) {
+	ds := newTestDatasource(t, alertTestAPI(), nil)
+	qm := queryModel{QueryType: "raw", Property: "sensor", Device: "web01", SensorId: "1001"}
+
+	qm.AdhocFilters = []adhocFilter{{Key: "status", Operator: "=", Value: "down"}}
+	res := ds.handlePropertyQuery(context.Background(), qm, "sensor", "status_raw", "A")
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if frame := res.Frames[0]; len(frame.Fields) != 0 || !hasNotice(frame, "does not match the ad-hoc filters") {
+		t.Errorf("filtered sensor returned %d fields, notices %v", len(frame.Fields), frame.Meta)
+	}
+
+	qm.AdhocFilters = []adhocFilter{{Key: "status", Operator: "=", Value: "up"}}
+	res = ds.handlePropertyQuery(context.Background(), qm, "sensor", "status_raw", "A")
+	if res.Error != nil || len(res.Frames[0].Fields) != 2 {
+		t.Fatalf("matching sensor: error %v, frames %v", res.Error, res.Frames)
+	}
+}
+
+func TestAdhocFiltersIgnoredNotice(t *testing.T) {
+	api := &fakeAPI{tables: func(content string, params map[string]string) []map[string]interface{} {
+		return []map[string]interface{}{{"objid": 10.0, "group": "Linux", "status": "Up", "status_raw": 3.0}}
+	}}
+	ds := newTestDatasource(t, api, nil)
+	qm := queryModel{
+		QueryType:    "table",
+		TableContent: "groups",
+		AdhocFilters: []adhocFilter{
+			{Key: "group", Operator: "=", Value: "Linux"},
+			{Key: "sensor", Operator: "=", Value: "Ping"},
+		},
+	}
+	res := ds.handleTableQuery(context.Background(), qm, "A")
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+	if frame.Rows() != 1 {
+		t.Errorf("got %d rows, want the group", frame.Rows())
+	}
+	if !hasNotice(frame, "sensor = Ping") || hasNotice(frame, "group = Linux") {
+		t.Errorf("notices = %v, want only the sensor filter reported", frame.Meta.Notices)
+	}
+	if got := api.tableRequests[0]["filter_group"]; got != "Linux" {
+		t.Errorf("filter_group = %q, want Linux", got)
+	}
+}
+
+func TestApplyAdhocParams(t *testing.T) {
+	matchers, err := newAdhocMatchers([]adhocFilter{
+		{Key: "status", Operator: "=", Value: "paused"},
+		{Key: "device", Operator: "=", Value: "web01"},
+		{Key: "type", Operator: "=", Value: "Ping"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := map[string]string{}
+	remaining, ignored := applyAdhocParams(matchers, "objid,device,status", params)
+
+	if params["filter_device"] != "web01" {
+		t.Errorf("params = %v, want filter_device", params)
+	}
+	if _, ok := params["filter_status"]; ok {
+		t.Errorf("paused has several codes and must be matched on the rows: %v", params)
+	}
+	if len(remaining) != 1 || remaining[0].Key != "status" {
+		t.Errorf("remaining = %v, want the status filter", remaining)
+	}
+	if len(ignored) != 1 || ignored[0].Key != "type" {
+		t.Errorf("ignored = %v, want the type filter", ignored)
+	}
+	if !remaining[0].matches(map[string]interface{}{"status": "Paused by Dependency", "status_raw": 8.0}) {
+		t.Error("paused filter does not match status 8")
+	}
+}
+
+func TestAdhocFiltersOnSensorSources(t *testing.T) {
+	// The server returns only the requested columns, as PRTG does
+	sensor := map[string]interface{}{
+		"objid": 1001, "probe": "Local Probe", "group": "Linux", "device": "web01", "sensor": "Ping",
+		"status": "Up", "status_raw": 3, "tags": "pingsensor", "type": "Ping", "type_raw": "ping",
+		"priority": "***", "priority_raw": 3, "interval": "60 s", "interval_raw": 60,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		row := map[string]interface{}{}
+		for _, column := range strings.Split(r.URL.Query().Get("columns"), ",") {
+			for _, key := range []string{column, column + "_raw"} {
+				if value, ok := sensor[key]; ok {
+					row[key] = value
+				}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"sensors": []interface{}{row}})
+	}))
+	defer server.Close()
+	api := NewApi(server.URL, "token", time.Minute, 5*time.Second)
+
+	sources := map[string]func() ([]PrtgSensorListItemStruct, error){
+		"GetSensorDetails": func() ([]PrtgSensorListItemStruct, error) {
+			s, err := api.GetSensorDetails("1001")
+			if err != nil {
+				return nil, err
+			}
+			return []PrtgSensorListItemStruct{*s}, nil
+		},
+		"FindSensors": func() ([]PrtgSensorListItemStruct, error) {
+			res, err := api.FindSensors("")
+			if err != nil {
+				return nil, err
+			}
+			return res.Sensors, nil
+		},
+		"GetSensors": func() ([]PrtgSensorListItemStruct, error) {
+			res, err := api.GetSensors("web01")
+			if err != nil {
+				return nil, err
+			}
+			return res.Sensors, nil
+		},
+	}
+	filters := []adhocFilter{
+		{Key: "probe", Operator: "=", Value: "Local Probe"},
+		{Key: "type", Operator: "=", Value: "Ping"},
+		{Key: "priority", Operator: ">", Value: "2"},
+		{Key: "tags", Operator: "=", Value: "pingsensor"},
+	}
+	for name, load := range sources {
+		sensors, err := load()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, f := range filters {
+			matchers, err := newAdhocMatchers([]adhocFilter{f})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := filterSensors(matchers, sensors); len(got) != 1 {
+				t.Errorf("%s: filter %s %s %s kept %d sensors, want 1", name, f.Key, f.Operator, f.Value, len(got))
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// defaultAlarmStates are the states of PRTG's alarm list, most severe first
+var defaultAlarmStates = []string{"down", "partial", "acknowledged", "warning", "unusual"}
+
+// alarmColumns are the sensor columns of an alarms query
+const alarmColumns = sensorTableColumns + ",message,downtimesince,lastup,lastdown,lastcheck,lastvalue"
+
+// ackPattern finds the user in the message of an acknowledged alarm,
+// e.g. "Acknowledged by PRTG System Administrator at 10.05.2025 08:00:00: ...".
+// PRTG has no column for it and writes the message in the language of the server, so the
+// pattern only matches English messages; otherwise "Acknowledged By" stays empty.
+var ackPattern = regexp.MustCompile(`(?i)acknowledged by (.+?)(?: at | on |:|$)`)
+
+// alarm is one sensor of the alarm list
+type alarm struct {
+	row      map[string]interface{}
+	rank     int // index of the state in the requested states
+	priority int64
+	duration *float64 // seconds in the current state
+}
+
+/* =================================== ALARMS QUERY ============================================ */
+
+// handleAlarmsQuery returns the sensors in an alarm state like PRTG's alarm list: one row per
+// sensor with path, cleaned message, priority, time in state and who acknowledged it.
+// The list can be restricted to group ids, a group pattern, tags, a minimum priority and ad-hoc filters.
+func (d *Datasource) handleAlarmsQuery(ctx context.Context, qm queryModel, frameName string) backend.DataResponse {
+	_, span := d.tracer.StartSpan(ctx, "handleAlarmsQuery")
+	defer span.End()
+
+	loc, err := d.queryLocation(qm.Timezone)
+	if err != nil {
+		d.metrics.IncError("invalid_timezone")
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	states := defaultAlarmStates
+	if len(qm.AlarmStates) > 0 {
+		states = qm.AlarmStates
+	}
+	// A state can have several status codes (paused), each keeps the rank of its state
+	codes := make([]string, 0, len(states))
+	ranks := make([]int, 0, len(states))
+	for i, state := range states {
+		stateCodes, ok := statusFilterCodes[strings.ToLower(state)]
+		if !ok {
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("unknown alarm state '%s'", state))
+		}
+		for _, code := range stateCodes {
+			codes = append(codes, code)
+			ranks = append(ranks, i)
+		}
+	}
+
+	groupPattern, err := compileNamePattern(qm.GroupFilter)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("invalid group filter: %v", err))
+	}
+	adhoc, err := newAdhocMatchers(qm.AdhocFilters)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	tags := make([]string, 0, len(qm.Tags))
+	for _, tag := range qm.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, strings.ToLower(tag))
+		}
+	}
+	parentIds := qm.groupIds()
+	if len(parentIds) == 0 {
+		parentIds = []string{""}
+	}
+
+	// PRTG accepts one filter_status per request, every state is loaded separately
+	now := time.Now()
+	seen := make(map[string]struct{})
+	alarms := make([]alarm, 0)
+	for i, code := range codes {
+		for _, parentId := range parentIds {
+			params := map[string]string{"columns": alarmColumns, "filter_status": code}
+			if parentId != "" {
+				params["id"] = parentId
+			}
+			remaining, _ := applyAdhocParams(adhoc, alarmColumns, params)
+
+			rows, err := d.api.GetLiveTable("sensors", params)
+			if err != nil {
+				d.logger.Error("Alarms query failed", "error", err)
+				recordError(span, err, "Alarms query failed")
+				return backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("API request failed: %v", err))
+			}
+			for _, row := range rows {
+				objid := rowString(row, "objid")
+				if _, ok := seen[objid]; ok || !matchesAdhoc(remaining, row) || !hasAllTags(rowString(row, "tags"), tags) {
+					continue
+				}
+				if groupPattern != nil && !groupPattern.MatchString(rowString(row, "group")) {
+					continue
+				}
+				a := alarm{row: row, rank: ranks[i]}
+				if priority, ok := rowFloat(row, "priority_raw"); ok {
+					a.priority = int64(priority)
+				}
+				if a.priority < qm.AlarmMinPriority {
+					continue
+				}
+				a.duration = alarmDuration(row, loc, now)
+				seen[objid] = struct{}{}
+				alarms = append(alarms, a)
+			}
+		}
+	}
+
+	// Most severe state first, then higher priority, then longer in the state
+	sort.SliceStable(alarms, func(i, j int) bool {
+		a, b := alarms[i], alarms[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+		if a.duration != nil && b.duration != nil {
+			return *a.duration > *b.duration
+		}
+		return a.duration != nil
+	})
+	if qm.Limit > 0 && int64(len(alarms)) > qm.Limit {
+		alarms = alarms[:qm.Limit]
+	}
+
+	frame := alarmFrame(frameName, alarms, now)
+	frame.Meta = &data.FrameMeta{
+		Type: data.FrameTypeTable,
+		Custom: map[string]interface{}{
+			"queryType": "alarms",
+			"alarms":    len(alarms),
+		},
+	}
+	return backend.DataResponse{Frames: []*data.Frame{frame}}
+}
+
+// alarmDuration returns the seconds a sensor is in its current state. PRTG reports them only for
+// down states (downtimesince); for warning and unusual sensors the last status change, the later of
+// the last up and last down time, is used and the last check if neither is known.
+func alarmDuration(row map[string]interface{}, loc *time.Location, now time.Time) *float64 {
+	if seconds, ok := rowFloat(row, "downtimesince_raw"); ok && seconds > 0 {
+		return &seconds
+	}
+	var since *time.Time
+	for _, column := range []string{"lastup_raw", "lastdown_raw"} {
+		if t := rowTime(row, column, loc); t != nil && (since == nil || t.After(*since)) {
+			since = t
+		}
+	}
+	if since == nil {
+		since = rowTime(row, "lastcheck_raw", loc)
+	}
+	if since == nil {
+		return nil
+	}
+	seconds := max(now.Sub(*since).Seconds(), 0)
+	return &seconds
+}
+
+// alarmFrame builds the table of the alarms, Time is the start of the current state
+func alarmFrame(frameName string, alarms []alarm, now time.Time) *data.Frame {
+	since := make([]*time.Time, len(alarms))
+	statuses := make([]string, len(alarms))
+	priorities := make([]int64, len(alarms))
+	probes := make([]string, len(alarms))
+	groups := make([]string, len(alarms))
+	devices := make([]string, len(alarms))
+	sensors := make([]string, len(alarms))
+	sensorIds := make([]int64, len(alarms))
+	messages := make([]string, len(alarms))
+	durations := make([]*float64, len(alarms))
+	acknowledgedBy := make([]string, len(alarms))
+	lastValues := make([]string, len(alarms))
+
+	for i, a := range alarms {
+		if a.duration != nil {
+			t := now.Add(-time.Duration(*a.duration * float64(time.Second)))
+			since[i] = &t
+		}
+		statuses[i] = rowString(a.row, "status")
+		priorities[i] = a.priority
+		probes[i] = rowString(a.row, "probe")
+		groups[i] = rowString(a.row, "group")
+		devices[i] = rowString(a.row, "device")
+		sensors[i] = rowString(a.row, "sensor")
+		sensorIds[i], _ = strconv.ParseInt(rowString(a.row, "objid"), 10, 64)
+		messages[i] = cleanMessageHTML(rowString(a.row, "message"))
+		durations[i] = a.duration
+		if hasStatus(a.row, statusFilterCodes["acknowledged"]) {
+			if m := ackPattern.FindStringSubmatch(messages[i]); m != nil {
+				acknowledgedBy[i] = strings.TrimSpace(m[1])
+			}
+		}
+		lastValues[i] = rowString(a.row, "lastvalue")
+	}
+
+	return data.NewFrame(frameName,
+		data.NewField("Time", nil, since),
+		data.NewField("Status", nil, statuses),
+		data.NewField("Priority", nil, priorities),
+		data.NewField("Probe", nil, probes),
+		data.NewField("Group", nil, groups),
+		data.NewField("Device", nil, devices),
+		data.NewField("Sensor", nil, sensors),
+		data.NewField("Sensor ID", nil, sensorIds),
+		data.NewField("Message", nil, messages),
+		data.NewField("Duration", nil, durations).SetConfig(&data.FieldConfig{Unit: "s"}),
+		data.NewField("Acknowledged By", nil, acknowledgedBy),
+		data.NewField("Last Value", nil, lastValues),
+	)
+}
@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAlarmDuration(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		row  map[string]interface{}
+		want float64
+	}{
+		{"down", map[string]interface{}{"downtimesince_raw": 600.0, "lastup_raw": oleDate(now.Add(-time.Hour))}, 600},
+		{"warning", map[string]interface{}{
+			"downtimesince_raw": "",
+			"lastup_raw":        oleDate(now.Add(-30 * time.Minute)),
+			"lastdown_raw":      oleDate(now.Add(-48 * time.Hour)),
+			"lastcheck_raw":     oleDate(now.Add(-time.Minute)),
+		}, 1800},
+		{"last check only", map[string]interface{}{"lastcheck_raw": oleDate(now.Add(-2 * time.Minute))}, 120},
+	}
+	for _, tt := range tests {
+		got := alarmDuration(tt.row, time.UTC, now)
+		if got == nil || *got < tt.want-1 || *got > tt.want+1 {
+			t.Errorf("%s: duration = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got := alarmDuration(map[string]interface{}{}, time.UTC, now); got != nil {
+		t.Errorf("duration without times = %v, want nil", *got)
+	}
+}
+
+func TestAlarmsQuery(t *testing.T) {
+	now := time.Now()
+	api := &fakeAPI{tables: func(content string, params map[string]string) []map[string]interface{} {
+		switch params["filter_status"] {
+		case "5":
+			return []map[string]interface{}{{
+				"objid": 1001.0, "sensor": "Ping", "device": "web01", "status": "Down", "status_raw": 5.0,
+				"priority_raw": 3.0, "downtimesince_raw": 300.0,
+			}}
+		case "13":
+			return []map[string]interface{}{{
+				"objid": 1002.0, "sensor": "HTTP", "device": "web01", "status": "Down (Acknowledged)", "status_raw": 13.0,
+				"priority_raw": 3.0, "downtimesince_raw": 900.0,
+				"message": "Acknowledged by PRTG System Administrator at 02.03.2026 11:00:00: planned",
+			}}
+		case "4":
+			return []map[string]interface{}{{
+				"objid": 1003.0, "sensor": "Disk", "device": "web01", "status": "Warning", "status_raw": 4.0,
+				"priority_raw": 3.0, "lastup_raw": oleDate(now.Add(-time.Hour)),
+			}}
+		}
+		return nil
+	}}
+	ds := newTestDatasource(t, api, nil)
+	res := ds.handleAlarmsQuery(context.Background(), queryModel{QueryType: "alarms"}, "A")
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	frame := res.Frames[0]
+	if frame.Rows() != 3 {
+		t.Fatalf("got %d alarms, want 3", frame.Rows())
+	}
+	sensors, _ := frame.FieldByName("Sensor")
+	durations, _ := frame.FieldByName("Duration")
+	acknowledged, _ := frame.FieldByName("Acknowledged By")
+	want := []struct {
+		sensor   string
+		duration float64
+		ackBy    string
+	}{
+		{"Ping", 300, ""},
+		{"HTTP", 900, "PRTG System Administrator"},
+		{"Disk", 3600, ""},
+	}
+	for i, w := range want {
+		if got := sensors.At(i).(string); got != w.sensor {
+			t.Errorf("row %d sensor = %q, want %q", i, got, w.sensor)
+		}
+		if got := durations.At(i).(*float64); got == nil || *got < w.duration-5 || *got > w.duration+5 {
+			t.Errorf("row %d duration = %v, want %v", i, got, w.duration)
+		}
+		if got := acknowledged.At(i).(string); got != w.ackBy {
+			t.Errorf("row %d acknowledged by = %q, want %q", i, got, w.ackBy)
+		}
+	}
+}
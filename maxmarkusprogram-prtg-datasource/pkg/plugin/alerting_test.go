@@ -0,0 +1,184 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+var alertEnd = time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+
+// oleDate converts a time to the OLE date of PRTG's datetime_raw columns
+func oleDate(t time.Time) float64 {
+	return float64(t.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC))) / float64(24*time.Hour)
+}
+
+// alertTestAPI serves a ping sensor with two historic rows
+func alertTestAPI() *fakeAPI {
+	sensor := PrtgSensorListItemStruct{
+		ObjectId: 1001, Group: "Linux", Device: "web01", Sensor: "Ping",
+		Status: "Up", StatusRAW: 3, DatetimeRAW: oleDate(alertEnd),
+	}
+	rows := []PrtgValues{
+		{DatetimeRAW: oleDate(alertEnd.Add(-2 * time.Minute)), Value: map[string]interface{}{"value_0": "12 ms", "value_0_raw": 12.0}},
+		{DatetimeRAW: oleDate(alertEnd.Add(-time.Minute)), Value: map[string]interface{}{"value_0": "15 ms", "value_0_raw": 15.0}},
+	}
+	return &fakeAPI{
+		sensors: map[string]*PrtgSensorListItemStruct{"1001": &sensor},
+		channels: map[string]*PrtgChannelListResponse{"1001": {Channels: []PrtgChannelListItemStruct{
+			{ObjectId: 0, Name: "Ping Time", Unit: "ms"},
+		}}},
+		historic:      map[string]*PrtgHistoricalDataResponse{"1001": {HistData: rows}},
+		deviceSensors: map[string]*PrtgSensorsListResponse{"web01": {Sensors: []PrtgSensorListItemStruct{sensor}}},
+	}
+}
+
+// queryAsAlert runs a query like an alert rule evaluation: no panel, alerting headers and the
+// query type only in the query model
+func queryAsAlert(t *testing.T, ds *Datasource, from time.Time, model map[string]interface{}) backend.DataResponse {
+	t.Helper()
+	raw, err := json.Marshal(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := ds.QueryData(context.Background(), &backend.QueryDataRequest{
+		Headers: map[string]string{"FromAlert": "true", "X-Rule-Uid": "rule-1"},
+		Queries: []backend.DataQuery{{
+			RefID:         "A",
+			JSON:          raw,
+			Interval:      time.Second,
+			MaxDataPoints: 43200,
+			TimeRange:     backend.TimeRange{From: from, To: alertEnd},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("QueryData: %v", err)
+	}
+	res, ok := resp.Responses["A"]
+	if !ok {
+		t.Fatal("no response for refId A")
+	}
+	if res.Error != nil {
+		t.Fatalf("query failed: %v", res.Error)
+	}
+	return res
+}
+
+func TestAlertMetricsQuery(t *testing.T) {
+	ds := newTestDatasource(t, alertTestAPI(), nil)
+	res := queryAsAlert(t, ds, alertEnd.Add(-time.Hour), map[string]interface{}{
+		"queryType":  "metrics",
+		"sensorId":   "1001",
+		"channelIds": []string{"0"},
+	})
+
+	if len(res.Frames) != 1 {
+		t.Fatalf("got %d frames, want 1", len(res.Frames))
+	}
+	frame := res.Frames[0]
+	if frame.Meta == nil || frame.Meta.Type != data.FrameTypeTimeSeriesWide || frame.Meta.TypeVersion != (data.FrameTypeVersion{0, 1}) {
+		t.Fatalf("frame meta = %+v, want time series wide 0.1", frame.Meta)
+	}
+	if schema := frame.TimeSeriesSchema(); schema.Type != data.TimeSeriesTypeWide {
+		t.Fatalf("frame is no wide time series: %v", schema.Type)
+	}
+	if len(frame.Fields) != 2 {
+		t.Fatalf("got %d fields, want time and value", len(frame.Fields))
+	}
+
+	value := frame.Fields[1]
+	if value.Type() != data.FieldTypeNullableFloat64 {
+		t.Fatalf("value field type = %v, want nullable float64", value.Type())
+	}
+	want := []float64{12, 15}
+	if value.Len() != len(want) {
+		t.Fatalf("got %d values, want %d", value.Len(), len(want))
+	}
+	for i, w := range want {
+		if got := value.At(i).(*float64); got == nil || *got != w {
+			t.Errorf("value %d = %v, want %v", i, got, w)
+		}
+	}
+	if value.Labels["sensor"] != "Ping" || value.Labels["device"] != "web01" {
+		t.Errorf("value labels = %v, want the sensor and device", value.Labels)
+	}
+	if value.Config != nil && value.Config.DisplayNameFromDS != "" {
+		t.Errorf("display name %q set without Include* option", value.Config.DisplayNameFromDS)
+	}
+}
+
+func TestAlertMetricsQueryWithIntervalBounds(t *testing.T) {
+	ds := newTestDatasource(t, alertTestAPI(), nil)
+	res := queryAsAlert(t, ds, alertEnd.Add(-36*time.Hour), map[string]interface{}{
+		"queryType":             "metrics",
+		"sensorId":              "1001",
+		"channelIds":            []string{"0"},
+		"includeIntervalBounds": true,
+	})
+
+	frame := res.Frames[0]
+	if len(frame.Fields) != 4 {
+		t.Fatalf("got %d fields, want time, value and the interval bounds", len(frame.Fields))
+	}
+	if frame.Meta == nil || frame.Meta.Type != data.FrameTypeUnknown {
+		t.Errorf("frame with interval bounds has type %q, want none", frame.Meta.Type)
+	}
+}
+
+func TestAlertPropertyQuery(t *testing.T) {
+	ds := newTestDatasource(t, alertTestAPI(), nil)
+	res := queryAsAlert(t, ds, alertEnd.Add(-time.Hour), map[string]interface{}{
+		"queryType":      "raw",
+		"property":       "sensor",
+		"filterProperty": "status_raw",
+		"device":         "web01",
+		"sensorId":       "1001",
+	})
+
+	if len(res.Frames) != 1 {
+		t.Fatalf("got %d frames, want 1", len(res.Frames))
+	}
+	frame := res.Frames[0]
+	if frame.Meta == nil || frame.Meta.Type != data.FrameTypeTimeSeriesWide {
+		t.Fatalf("frame meta = %+v, want time series wide", frame.Meta)
+	}
+	value := frame.Fields[1]
+	if value.Type() != data.FieldTypeFloat64 {
+		t.Fatalf("value field type = %v, want float64", value.Type())
+	}
+	if got := value.At(0).(float64); got != 3 {
+		t.Errorf("status_raw = %v, want 3", got)
+	}
+}
+
+func TestAlertLastValueQuery(t *testing.T) {
+	api := alertTestAPI()
+	api.tables = func(content string, params map[string]string) []map[string]interface{} {
+		return []map[string]interface{}{{
+			"objid": 1001.0, "group": "Linux", "device": "web01", "sensor": "Ping",
+			"status": "Up", "status_raw": 3.0, "lastvalue": "15 ms", "lastvalue_raw": 15.0,
+			"lastcheck_raw": oleDate(alertEnd),
+		}}
+	}
+	ds := newTestDatasource(t, api, nil)
+	res := queryAsAlert(t, ds, alertEnd.Add(-time.Hour), map[string]interface{}{
+		"queryType": "lastvalue",
+		"sensorId":  "1001",
+	})
+
+	frame := res.Frames[0]
+	if frame.Meta == nil || frame.Meta.Type != data.FrameTypeTable {
+		t.Fatalf("frame meta = %+v, want table", frame.Meta)
+	}
+	value, _ := frame.FieldByName("Value")
+	if value == nil || value.Len() != 1 {
+		t.Fatalf("last value frame has no value row: %v", frame.Fields)
+	}
+	if got := value.At(0).(*float64); got == nil || *got != 15 {
+		t.Errorf("last value = %v, want 15", got)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -180,4 +182,76 @@ func (d *Datasource) handleGetChannel(sender backend.CallResourceResponseSender,
 		Headers: map[string][]string{"Content-Type": {"application/json"}},
 		Body:    body,
 	})
-}
\ No newline at end of file
+}
+
+/* ######################################### handleGetVariables ############################################################*/
+// handleGetVariables serves variables?kind=devices&group=...&tags=a,b&status=up&regex=...
+func (d *Datasource) handleGetVariables(sender backend.CallResourceResponseSender, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return sendErrorResponse(sender, fmt.Sprintf("invalid url: %v", err), http.StatusBadRequest)
+	}
+	params := u.Query()
+
+	vq := variableQuery{
+		Kind:       params.Get("kind"),
+		ParentId:   params.Get("parentId"),
+		Group:      params.Get("group"),
+		Device:     params.Get("device"),
+		Status:     params.Get("status"),
+		SensorType: params.Get("type"),
+		Regex:      params.Get("regex"),
+		ValueField: params.Get("valueField"),
+	}
+	if tags := params.Get("tags"); tags != "" {
+		vq.Tags = strings.Split(tags, ",")
+	}
+
+	options, err := d.variableOptions(vq)
+	if err != nil {
+		return sendErrorResponse(sender, err.Error(), http.StatusBadRequest)
+	}
+	return sendJSONResponse(sender, options)
+}
+
+/* ######################################### handleGetTagKeys ############################################################*/
+// handleGetTagKeys lists the keys of ad-hoc filters
+func (d *Datasource) handleGetTagKeys(sender backend.CallResourceResponseSender) error {
+	keys := make([]map[string]string, 0, len(adhocFilterKeys))
+	for _, key := range adhocFilterKeys {
+		keys = append(keys, map[string]string{"text": key})
+	}
+	return sendJSONResponse(sender, keys)
+}
+
+/* ######################################### handleGetTagValues ############################################################*/
+// handleGetTagValues lists the values of an ad-hoc filter key: tag-values?key=status
+func (d *Datasource) handleGetTagValues(sender backend.CallResourceResponseSender, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return sendErrorResponse(sender, fmt.Sprintf("invalid url: %v", err), http.StatusBadRequest)
+	}
+
+	values, err := d.adhocTagValues(u.Query().Get("key"))
+	if err != nil {
+		return sendErrorResponse(sender, err.Error(), http.StatusBadRequest)
+	}
+	result := make([]map[string]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, map[string]string{"text": value})
+	}
+	return sendJSONResponse(sender, result)
+}
+
+// sendJSONResponse sends the value as JSON with status 200
+func sendJSONResponse(sender backend.CallResourceResponseSender, value interface{}) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return sendErrorResponse(sender, fmt.Sprintf("error marshaling response: %v", err), http.StatusInternalServerError)
+	}
+	return sender.Send(&backend.CallResourceResponse{
+		Status:  http.StatusOK,
+		Headers: map[string][]string{"Content-Type": {"application/json"}},
+		Body:    body,
+	})
+}
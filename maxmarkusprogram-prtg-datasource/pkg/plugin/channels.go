@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* =================================== CHANNEL LOOKUP ========================================== */
+
+// channelInfoByName indexes the channel table of a sensor by channel name.
+func channelInfoByName(channels *PrtgChannelListResponse) map[string]*PrtgChannelListItemStruct {
+	result := make(map[string]*PrtgChannelListItemStruct)
+	if channels == nil {
+		return result
+	}
+	for i := range channels.Channels {
+		result[channels.Channels[i].Name] = &channels.Channels[i]
+	}
+	return result
+}
+
+// channelInfoByID indexes the channel table of a sensor by channel id.
+func channelInfoByID(channels *PrtgChannelListResponse) map[string]*PrtgChannelListItemStruct {
+	result := make(map[string]*PrtgChannelListItemStruct)
+	if channels == nil {
+		return result
+	}
+	for i := range channels.Channels {
+		result[strconv.FormatInt(channels.Channels[i].ObjectId, 10)] = &channels.Channels[i]
+	}
+	return result
+}
+
+// findChannelInfo resolves the channel for a historic data column. Historic data
+// columns may carry a suffix like "Traffic In (speed)" for the channel "Traffic In".
+func findChannelInfo(channels map[string]*PrtgChannelListItemStruct, column string) *PrtgChannelListItemStruct {
+	if info, ok := channels[column]; ok {
+		return info
+	}
+	if idx := strings.LastIndex(column, " ("); idx > 0 {
+		if info, ok := channels[column[:idx]]; ok {
+			return info
+		}
+	}
+	return nil
+}
+
+/* =================================== CHANNEL SELECTION ======================================= */
+
+// channelSelection is one channel requested by a metrics query.
+type channelSelection struct {
+	ID     string                     // PRTG channel id, empty if it could not be resolved
+	Name   string                     // current channel caption, used for display
+	Column string                     // column of the channel in the historic data
+	Info   *PrtgChannelListItemStruct // channel settings, nil if unknown
+}
+
+// channelNotFoundError reports a selected channel the sensor does not have
+type channelNotFoundError struct {
+	channel  string
+	sensorId string
+}
+
+func (e *channelNotFoundError) Error() string {
+	return fmt.Sprintf("channel %s not found on sensor %s", e.channel, e.sensorId)
+}
+
+// resolveChannelSelections maps the channels of a query to the current channel table.
+// Channel ids are preferred; captions are only used for queries saved before ids were stored.
+func resolveChannelSelections(qm queryModel, channels *PrtgChannelListResponse) ([]channelSelection, error) {
+	selections := make([]channelSelection, 0)
+
+	if len(qm.ChannelIds) > 0 && channels != nil {
+		byID := channelInfoByID(channels)
+		for _, id := range qm.ChannelIds {
+			info, ok := byID[id]
+			if !ok {
+				return nil, &channelNotFoundError{channel: id, sensorId: qm.SensorId}
+			}
+			selections = append(selections, channelSelection{
+				ID:     id,
+				Name:   info.Name,
+				Column: info.Name,
+				Info:   info,
+			})
+		}
+		return selections, nil
+	}
+
+	// Backward compatibility: queries that only know the channel captions
+	captions := qm.ChannelArray
+	if len(captions) == 0 && qm.Channel != "" {
+		captions = []string{qm.Channel}
+	}
+	if len(captions) == 0 {
+		return nil, fmt.Errorf("channel selection required")
+	}
+
+	byName := channelInfoByName(channels)
+	for _, caption := range captions {
+		selection := channelSelection{
+			Name:   caption,
+			Column: caption,
+		}
+		if info := findChannelInfo(byName, caption); info != nil {
+			selection.ID = strconv.FormatInt(info.ObjectId, 10)
+			selection.Info = info
+		}
+		selections = append(selections, selection)
+	}
+	return selections, nil
+}
+
+// historicIdColumn matches historic data columns keyed by channel id: "2" or "value_2".
+// Captions with a number like "Core (1)" are not ids, they are matched by caption.
+var historicIdColumn = regexp.MustCompile(`^(?:value_)?(\d+)$`)
+
+// historicColumnChannelID returns the channel id of a historic data column
+func historicColumnChannelID(column string) (string, bool) {
+	for _, suffix := range rawColumnSuffixes {
+		if strings.HasSuffix(column, suffix) {
+			return "", false
+		}
+	}
+	match := historicIdColumn.FindStringSubmatch(column)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// mapHistoricColumns assigns every selected channel the column it has in the historic data.
+// Columns are matched by channel id. Only if PRTG returns no id columns (older versions) the
+// channel caption is used, optionally with a suffix like " (speed)".
+func mapHistoricColumns(historicalData *PrtgHistoricalDataResponse, selections []channelSelection) {
+	if historicalData == nil || len(historicalData.HistData) == 0 {
+		return
+	}
+
+	columns := make([]string, 0, len(historicalData.HistData[0].Value))
+	byID := make(map[string]string)
+	for column := range historicalData.HistData[0].Value {
+		columns = append(columns, column)
+		if id, ok := historicColumnChannelID(column); ok {
+			byID[id] = column
+		}
+	}
+	sort.Strings(columns)
+
+	for i := range selections {
+		if column, ok := byID[selections[i].ID]; ok && selections[i].ID != "" {
+			selections[i].Column = column
+			continue
+		}
+		if len(byID) > 0 {
+			continue
+		}
+		if _, ok := historicalData.HistData[0].Value[selections[i].Column]; ok {
+			continue
+		}
+		for _, column := range columns {
+			if strings.HasPrefix(column, selections[i].Name+" (") {
+				selections[i].Column = column
+				break
+			}
+		}
+	}
+}
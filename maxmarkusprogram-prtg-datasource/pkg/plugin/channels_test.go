@@ -0,0 +1,77 @@
+package plugin
+
+import "testing"
+
+func TestMapHistoricColumnsByID(t *testing.T) {
+	historicalData := &PrtgHistoricalDataResponse{HistData: []PrtgValues{{
+		Value: map[string]interface{}{
+			"value_0":     "12 ms",
+			"value_0_raw": 12.0,
+			"value_2":     "3 %",
+			"value_2_raw": 3.0,
+		},
+	}}}
+	selections := []channelSelection{
+		{ID: "2", Name: "Packet Loss", Column: "Packet Loss"},
+		{ID: "0", Name: "Ping Time", Column: "Ping Time"},
+		{ID: "7", Name: "Renamed", Column: "Renamed"},
+	}
+	mapHistoricColumns(historicalData, selections)
+
+	want := []string{"value_2", "value_0", "Renamed"}
+	for i, sel := range selections {
+		if sel.Column != want[i] {
+			t.Errorf("channel %s mapped to %q, want %q", sel.ID, sel.Column, want[i])
+		}
+	}
+}
+
+func TestMapHistoricColumnsByCaption(t *testing.T) {
+	historicalData := &PrtgHistoricalDataResponse{HistData: []PrtgValues{{
+		Value: map[string]interface{}{
+			"Traffic In (speed)":       "1 kbit/s",
+			"Traffic In (speed) (RAW)": 125.0,
+		},
+	}}}
+	selections := []channelSelection{{ID: "-1", Name: "Traffic In", Column: "Traffic In"}}
+	mapHistoricColumns(historicalData, selections)
+
+	if selections[0].Column != "Traffic In (speed)" {
+		t.Errorf("channel mapped to %q, want %q", selections[0].Column, "Traffic In (speed)")
+	}
+}
+
+func TestMapHistoricColumnsNumberedCaptions(t *testing.T) {
+	// Servers returning only captions, a number in parentheses is part of the caption
+	historicalData := &PrtgHistoricalDataResponse{HistData: []PrtgValues{{
+		Value: map[string]interface{}{
+			"Core (1)":            "12 %",
+			"Core (1) (RAW)":      12.0,
+			"Total (%)":           "8 %",
+			"Total (%) (RAW)":     8.0,
+			"Free Memory":         "1 GB",
+			"Free Memory (RAW)":   1e9,
+			"Traffic (2) (speed)": "3 kbit/s",
+		},
+	}}}
+	selections := []channelSelection{
+		{ID: "1", Name: "Core (1)", Column: "Core (1)"},
+		{ID: "0", Name: "Total", Column: "Total"},
+		{ID: "3", Name: "Free Memory", Column: "Free Memory"},
+		{ID: "2", Name: "Traffic (2)", Column: "Traffic (2)"},
+	}
+	mapHistoricColumns(historicalData, selections)
+
+	want := []string{"Core (1)", "Total (%)", "Free Memory", "Traffic (2) (speed)"}
+	for i, sel := range selections {
+		if sel.Column != want[i] {
+			t.Errorf("channel %s mapped to %q, want %q", sel.ID, sel.Column, want[i])
+		}
+	}
+
+	for column, want := range map[string]string{"2": "2", "value_2": "2", "Core (1)": "", "value_2 (RAW)": ""} {
+		if id, _ := historicColumnChannelID(column); id != want {
+			t.Errorf("historicColumnChannelID(%q) = %q, want %q", column, id, want)
+		}
+	}
+}
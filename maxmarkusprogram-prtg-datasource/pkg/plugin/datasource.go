@@ -44,9 +44,6 @@ func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSetti
 		return nil, err
 	}
 
-	// Set the default timezone from config (frontend)
-	SetDefaultTimezone(config.Timezone)
-
 	// Get cache time from settings with default
 	var cacheTime time.Duration = 60 * time.Second // default 60 seconds
 	if config.CacheTime > 0 {
@@ -60,14 +57,17 @@ func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSetti
 
 	// Use apitoken parameter name to match PRTG API requirements
 	ds := &Datasource{
-		baseURL:    baseURL,
-		api:        NewApi(baseURL, config.Secrets.ApiKey, cacheTime, 10*time.Second),
-		logger:     logger,
-		tracer:     tracer,
-		metrics:    metrics,
-		queryCache: make(map[string]*QueryCacheEntry), // Updated initialization
-		cacheMutex: sync.RWMutex{},
-		cacheTime:  cacheTime,
+		baseURL:          baseURL,
+		api:              NewApi(baseURL, config.Secrets.ApiKey, cacheTime, 10*time.Second),
+		logger:           logger,
+		tracer:           tracer,
+		metrics:          metrics,
+		queryCache:       make(map[string]*QueryCacheEntry), // Updated initialization
+		cacheMutex:       sync.RWMutex{},
+		cacheTime:        cacheTime,
+		decimalSeparator: config.DecimalSeparator,
+		settings:         config,
+		loc:              loadTimezone(config.Timezone),
 		streamManager: &streamManager{
 			streams:          make(map[string]*activeStream),
 			activeStreams:    make(map[string]map[string]*activeStream), // Map of panel -> streams
@@ -76,17 +76,51 @@ func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSetti
 	}
 
 	// Initialize query type multiplexer
-	queryTypeMux := datasource.NewQueryTypeMux()
-	queryTypeMux.HandleFunc("metrics", ds.handleMetricsQueryType)
-	queryTypeMux.HandleFunc("manual", ds.handleManualQueryType)
-	queryTypeMux.HandleFunc("text", ds.handlePropertyQueryType)
-	queryTypeMux.HandleFunc("raw", ds.handlePropertyQueryType)
-	queryTypeMux.HandleFunc("", ds.handleQueryFallback)
+	ds.mux = ds.newQueryTypeMux()
 
-	ds.mux = queryTypeMux
 	return ds, nil
 }
 
+// newQueryTypeMux routes the queries of a request to the handler of their query type
+func (d *Datasource) newQueryTypeMux() *datasource.QueryTypeMux {
+	queryTypeMux := datasource.NewQueryTypeMux()
+	queryTypeMux.HandleFunc("metrics", d.handleMetricsQueryType)
+	queryTypeMux.HandleFunc("search", d.handleMetricsQueryType) // multi-sensor metrics, same execution path
+	queryTypeMux.HandleFunc("variables", d.handleMetricsQueryType)
+	queryTypeMux.HandleFunc("table", d.handleMetricsQueryType)
+	queryTypeMux.HandleFunc("lastvalue", d.handleMetricsQueryType)
+	queryTypeMux.HandleFunc("alarms", d.handleMetricsQueryType)
+	queryTypeMux.HandleFunc("logs", d.handleMetricsQueryType)
+	queryTypeMux.HandleFunc("manual", d.handleManualQueryType)
+	queryTypeMux.HandleFunc("text", d.handlePropertyQueryType)
+	queryTypeMux.HandleFunc("raw", d.handlePropertyQueryType)
+	queryTypeMux.HandleFunc("", d.handleQueryFallback)
+	return queryTypeMux
+}
+
+// applyServerTime detects the timezone of the PRTG server, logs a warning if it does not match
+// the configured timezone and switches to the detected offset if autoTimezone is enabled.
+func (d *Datasource) applyServerTime(status *PrtgStatusListResponse, config *models.PluginSettings) (*serverTimeInfo, []string) {
+	info, err := detectServerTime(status, time.Now())
+	if err != nil {
+		d.logger.Warn("Failed to detect PRTG server time", "error", err)
+		return nil, nil
+	}
+
+	loc, warnings := checkServerTimezone(info, config.Timezone, config.AutoTimezone, time.Now())
+	for _, warning := range warnings {
+		d.logger.Warn(warning,
+			"timezone", config.Timezone,
+			"serverOffset", formatUTCOffset(info.Offset),
+			"clockSkew", info.Skew,
+		)
+	}
+	if config.AutoTimezone {
+		d.setLocation(loc)
+	}
+	return info, warnings
+}
+
 /*  ########################################### Dispose ################################################### */
 func (d *Datasource) Dispose() {
 	// Clear caches on disposal
@@ -168,25 +202,25 @@ func (d *Datasource) QueryData(ctx context.Context, req *backend.QueryDataReques
 		}, nil
 	}
 
-	// Check maximum concurrent query limit
+	// Check maximum concurrent query limit, every query needs a response (e.g. in alert rules)
 	if len(req.Queries) > MaxConcurrentQueries {
-		return &backend.QueryDataResponse{
-			Responses: map[string]backend.DataResponse{
-				req.Queries[0].RefID: {
-					Error:  fmt.Errorf("query limit exceeded: %d/%d", len(req.Queries), MaxConcurrentQueries),
-					Status: backend.StatusTooManyRequests,
-				},
-			},
-		}, nil
+		response := backend.NewQueryDataResponse()
+		for _, q := range req.Queries {
+			response.Responses[q.RefID] = backend.ErrDataResponse(backend.StatusTooManyRequests,
+				fmt.Sprintf("query limit exceeded: %d/%d", len(req.Queries), MaxConcurrentQueries))
+		}
+		return response, nil
 	}
 
 	// Generate a stable cache key
 	cacheKey := generateCacheKey(req)
 	d.cacheMutex.RLock()
-	if cached, exists := d.queryCache[cacheKey]; exists && time.Now().Before(cached.ValidUntil) {
+	if cached, exists := d.queryCache[cacheKey]; exists && time.Now().Before(cached.ValidUntil) && cached.Responses != nil {
 		d.cacheMutex.RUnlock()
 		response := backend.NewQueryDataResponse()
-		response.Responses[req.Queries[0].RefID] = cached.Response
+		for refID, res := range cached.Responses {
+			response.Responses[refID] = res
+		}
 		return response, nil
 	}
 	d.cacheMutex.RUnlock()
@@ -197,10 +231,19 @@ func (d *Datasource) QueryData(ctx context.Context, req *backend.QueryDataReques
 		return nil, err
 	}
 
-	// Cache the result
+	// Cache the result if all queries succeeded
+	for _, res := range response.Responses {
+		if res.Error != nil {
+			return response, nil
+		}
+	}
+	responses := make(backend.Responses, len(response.Responses))
+	for refID, res := range response.Responses {
+		responses[refID] = res
+	}
 	d.cacheMutex.Lock()
 	d.queryCache[cacheKey] = &QueryCacheEntry{
-		Response:   response.Responses[req.Queries[0].RefID],
+		Responses:  responses,
 		ValidUntil: time.Now().Add(d.cacheTime),
 		Updating:   false,
 	}
@@ -274,9 +317,11 @@ func (d *Datasource) handlePropertyQueryType(ctx context.Context, req *backend.Q
 	return response, nil
 }
 
+// handleQueryFallback runs queries without query type, e.g. alert rules of older dashboards.
+// executeQuery takes the type from the query model and defaults to metrics.
 func (d *Datasource) handleQueryFallback(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	d.logger.Warn("Query type not supported", "queries", len(req.Queries))
-	return backend.NewQueryDataResponse(), nil
+	d.logger.Debug("Query without query type, using query model", "queries", len(req.Queries))
+	return d.handleMetricsQueryType(ctx, req)
 }
 
 /* ######################################## CheckHealth ##############################################################  */
@@ -318,6 +363,20 @@ func (d *Datasource) CheckHealth(ctx context.Context, req *backend.CheckHealthRe
 		message = fmt.Sprintf("Data source is working. PRTG Version: %s | Timezone: %s", status.Version, timezone)
 	}
 
+	// Check the PRTG server clock against the configured timezone
+	if config != nil {
+		info, warnings := d.applyServerTime(status, config)
+		if info != nil {
+			details["serverOffset"] = formatUTCOffset(info.Offset)
+			details["clockSkew"] = info.Skew.String()
+			details["autoTimezone"] = config.AutoTimezone
+		}
+		if len(warnings) > 0 {
+			details["timezoneWarnings"] = warnings
+			message = fmt.Sprintf("%s | Warning: %s", message, strings.Join(warnings, "; "))
+		}
+	}
+
 	detailsJSON, _ := json.Marshal(details)
 
 	return &backend.CheckHealthResult{
@@ -379,6 +438,15 @@ func (d *Datasource) processRequest(req *ResourceRequest) error {
 		}
 		return d.handleGetSensors(req.Sender, pathParts[1])
 
+	case strings.HasPrefix(path, "tag-keys"):
+		return d.handleGetTagKeys(req.Sender)
+
+	case strings.HasPrefix(path, "tag-values"):
+		return d.handleGetTagValues(req.Sender, req.Request.URL)
+
+	case strings.HasPrefix(path, "variables"):
+		return d.handleGetVariables(req.Sender, req.Request.URL)
+
 	case strings.HasPrefix(path, "channels/"):
 		pathParts := strings.Split(path, "/")
 		if len(pathParts) < 2 {
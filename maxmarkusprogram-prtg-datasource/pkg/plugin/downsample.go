@@ -0,0 +1,268 @@
+package plugin
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Downsampling modes of a metrics query
+const (
+	DownsampleMin  = "min"  // lowest value per bucket
+	DownsampleMax  = "max"  // highest value per bucket
+	DownsampleMean = "mean" // average per bucket
+	DownsampleLTTB = "lttb" // largest triangle three buckets, keeps peaks and the shape of the curve
+)
+
+/* =================================== DOWNSAMPLING ============================================ */
+
+// downsample reduces the table to about maxPoints rows. All columns share the resulting rows.
+// Bucket modes produce maxPoints rows plus the gap markers, LTTB keeps the union of the rows
+// selected per column. Returns false if the table was left unchanged.
+func (t *metricTable) downsample(mode string, maxPoints int) bool {
+	if maxPoints <= 0 || len(t.Times) <= maxPoints {
+		return false
+	}
+
+	switch mode {
+	case DownsampleMin, DownsampleMax, DownsampleMean:
+		t.aggregateBuckets(mode, maxPoints)
+	case DownsampleLTTB:
+		t.keepRows(t.lttbRows(maxPoints))
+	default:
+		return false
+	}
+	return true
+}
+
+// isGapRow reports whether a row has no value in any column, e.g. a marker from insertGaps
+func (t *metricTable) isGapRow(row int) bool {
+	for _, column := range t.Columns {
+		if column.Values[row] != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// aggregateBuckets groups consecutive rows into maxPoints buckets, each stamped with its first
+// timestamp. Gap rows split their bucket and are kept as null rows, so gaps survive downsampling.
+func (t *metricTable) aggregateBuckets(mode string, maxPoints int) {
+	rows := len(t.Times)
+	hasBounds := t.hasIntervalBounds()
+
+	times := make([]time.Time, 0, maxPoints)
+	values := make([][]*float64, len(t.Columns))
+	starts := make([]*time.Time, 0, maxPoints)
+	ends := make([]*time.Time, 0, maxPoints)
+
+	// appendRows adds the aggregate of the rows from:to as one row
+	appendRows := func(from, to int) {
+		if from >= to {
+			return
+		}
+		times = append(times, t.Times[from])
+		for c, column := range t.Columns {
+			values[c] = append(values[c], aggregateValues(mode, column.Values[from:to]))
+		}
+		if hasBounds {
+			starts = append(starts, firstTime(t.IntervalStarts[from:to]))
+			ends = append(ends, lastTime(t.IntervalEnds[from:to]))
+		}
+	}
+
+	for b := 0; b < maxPoints; b++ {
+		from := b * rows / maxPoints
+		to := (b + 1) * rows / maxPoints
+
+		segment := from
+		for row := from; row < to; row++ {
+			if !t.isGapRow(row) {
+				continue
+			}
+			appendRows(segment, row)
+			appendRows(row, row+1)
+			segment = row + 1
+		}
+		appendRows(segment, to)
+	}
+
+	t.Times = times
+	for c, column := range t.Columns {
+		column.Values = values[c]
+	}
+	if hasBounds {
+		t.IntervalStarts = starts
+		t.IntervalEnds = ends
+	}
+}
+
+// aggregateValues returns min, max or mean of the non-null values, nil if there are none
+func aggregateValues(mode string, values []*float64) *float64 {
+	var result float64
+	count := 0
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		switch {
+		case count == 0:
+			result = *value
+		case mode == DownsampleMin:
+			result = math.Min(result, *value)
+		case mode == DownsampleMax:
+			result = math.Max(result, *value)
+		default:
+			result += *value
+		}
+		count++
+	}
+
+	if count == 0 {
+		return nil
+	}
+	if mode == DownsampleMean {
+		result /= float64(count)
+	}
+	return &result
+}
+
+func firstTime(times []*time.Time) *time.Time {
+	for _, t := range times {
+		if t != nil {
+			return t
+		}
+	}
+	return nil
+}
+
+func lastTime(times []*time.Time) *time.Time {
+	for i := len(times) - 1; i >= 0; i-- {
+		if times[i] != nil {
+			return times[i]
+		}
+	}
+	return nil
+}
+
+// lttbRows runs LTTB on every column and returns the sorted union of the selected rows.
+// Rows without any value are gap markers and always kept.
+func (t *metricTable) lttbRows(maxPoints int) []int {
+	selected := make(map[int]struct{})
+
+	for row := range t.Times {
+		if t.isGapRow(row) {
+			selected[row] = struct{}{}
+		}
+	}
+
+	for _, column := range t.Columns {
+		points := make([]lttbPoint, 0, len(t.Times))
+		for row, value := range column.Values {
+			if value != nil {
+				points = append(points, lttbPoint{row: row, x: float64(t.Times[row].UnixMilli()), y: *value})
+			}
+		}
+		for _, row := range lttb(points, maxPoints) {
+			selected[row] = struct{}{}
+		}
+	}
+
+	rows := make([]int, 0, len(selected))
+	for row := range selected {
+		rows = append(rows, row)
+	}
+	sort.Ints(rows)
+	return rows
+}
+
+// keepRows reduces the table to the given rows, which must be sorted
+func (t *metricTable) keepRows(rows []int) {
+	hasBounds := t.hasIntervalBounds()
+
+	times := make([]time.Time, len(rows))
+	for i, row := range rows {
+		times[i] = t.Times[row]
+	}
+	for _, column := range t.Columns {
+		values := make([]*float64, len(rows))
+		for i, row := range rows {
+			values[i] = column.Values[row]
+		}
+		column.Values = values
+	}
+	if hasBounds {
+		starts := make([]*time.Time, len(rows))
+		ends := make([]*time.Time, len(rows))
+		for i, row := range rows {
+			starts[i] = t.IntervalStarts[row]
+			ends[i] = t.IntervalEnds[row]
+		}
+		t.IntervalStarts = starts
+		t.IntervalEnds = ends
+	}
+	t.Times = times
+}
+
+type lttbPoint struct {
+	row  int
+	x, y float64
+}
+
+// lttb implements the Largest-Triangle-Three-Buckets algorithm by Sveinn Steinarsson.
+// It returns the table rows of the threshold points that best preserve the visual shape.
+func lttb(points []lttbPoint, threshold int) []int {
+	if threshold < 3 {
+		threshold = 3
+	}
+	if len(points) <= threshold {
+		rows := make([]int, len(points))
+		for i, p := range points {
+			rows[i] = p.row
+		}
+		return rows
+	}
+
+	rows := make([]int, 0, threshold)
+	rows = append(rows, points[0].row)
+
+	// Bucket size without first and last point, which are always kept
+	every := float64(len(points)-2) / float64(threshold-2)
+	a := 0
+
+	for i := 0; i < threshold-2; i++ {
+		// Average of the next bucket is the third point of the triangle
+		avgStart := int(math.Floor(float64(i+1)*every)) + 1
+		avgEnd := int(math.Floor(float64(i+2)*every)) + 1
+		if avgEnd > len(points) {
+			avgEnd = len(points)
+		}
+		var avgX, avgY float64
+		for _, p := range points[avgStart:avgEnd] {
+			avgX += p.x
+			avgY += p.y
+		}
+		count := float64(avgEnd - avgStart)
+		avgX /= count
+		avgY /= count
+
+		// Pick the point of the current bucket spanning the largest triangle
+		rangeStart := int(math.Floor(float64(i)*every)) + 1
+		rangeEnd := int(math.Floor(float64(i+1)*every)) + 1
+		maxArea := -1.0
+		next := rangeStart
+		for j := rangeStart; j < rangeEnd; j++ {
+			area := math.Abs((points[a].x-avgX)*(points[j].y-points[a].y) -
+				(points[a].x-points[j].x)*(avgY-points[a].y))
+			if area > maxArea {
+				maxArea = area
+				next = j
+			}
+		}
+
+		rows = append(rows, points[next].row)
+		a = next
+	}
+
+	return append(rows, points[len(points)-1].row)
+}
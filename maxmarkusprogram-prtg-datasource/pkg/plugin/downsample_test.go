@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func gapTable() *metricTable {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	column := &metricColumn{}
+	table := &metricTable{Columns: []*metricColumn{column}}
+	for i := 0; i < 20; i++ {
+		table.Times = append(table.Times, start.Add(time.Duration(i)*time.Minute))
+		if i == 9 {
+			column.Values = append(column.Values, nil) // gap marker
+			continue
+		}
+		v := float64(i)
+		column.Values = append(column.Values, &v)
+	}
+	return table
+}
+
+func TestDownsampleKeepsGaps(t *testing.T) {
+	for _, mode := range []string{DownsampleMin, DownsampleMax, DownsampleMean, DownsampleLTTB} {
+		table := gapTable()
+		gapTime := table.Times[9]
+		if !table.downsample(mode, 4) {
+			t.Fatalf("%s: table was not downsampled", mode)
+		}
+
+		found := false
+		for i, ts := range table.Times {
+			if ts.Equal(gapTime) && table.Columns[0].Values[i] == nil {
+				found = true
+			}
+			if i > 0 && !ts.After(table.Times[i-1]) {
+				t.Errorf("%s: timestamps not increasing at row %d", mode, i)
+			}
+		}
+		if !found {
+			t.Errorf("%s: gap at %s lost after downsampling", mode, gapTime)
+		}
+	}
+}
+
+func TestAggregateBucketsSplitsAtGap(t *testing.T) {
+	table := gapTable()
+	table.downsample(DownsampleMean, 2)
+
+	// Bucket 0..9 is split by the gap: mean of 0..8, the gap, bucket 10..19
+	want := []*float64{ptr(4), nil, ptr(14.5)}
+	if len(table.Times) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(table.Times), len(want))
+	}
+	for i, w := range want {
+		got := table.Columns[0].Values[i]
+		if (got == nil) != (w == nil) || (got != nil && *got != *w) {
+			t.Errorf("row %d = %v, want %v", i, got, w)
+		}
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
@@ -0,0 +1,354 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"unicode"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// expressionSeries names one channel for use in the expression of a metrics query
+type expressionSeries struct {
+	// Identifier used in the expression, letters, digits and underscores
+	Name string `json:"name"`
+	// Sensor of the channel, defaults to the sensor of the query
+	SensorId string `json:"sensorId"`
+	// Channel id, preferred over the caption in Channel
+	ChannelId string `json:"channelId"`
+	Channel   string `json:"channel"`
+}
+
+/* =================================== EXPRESSION PARSER ======================================= */
+
+// exprFunc evaluates a parsed expression with the values of the named series at one point in time
+type exprFunc func(vars map[string]float64) float64
+
+// exprParser is a recursive descent parser for + - * / with parentheses, numbers and series names
+type exprParser struct {
+	input []rune
+	pos   int
+	names map[string]struct{}
+}
+
+// parseExpression parses an arithmetic expression and returns it with the series names it uses
+func parseExpression(expression string) (exprFunc, map[string]struct{}, error) {
+	p := &exprParser{input: []rune(expression), names: make(map[string]struct{})}
+	fn, err := p.parseSum()
+	if err != nil {
+		return nil, nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, nil, fmt.Errorf("unexpected '%c' at position %d", p.input[p.pos], p.pos+1)
+	}
+	return fn, p.names, nil
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space character, 0 at the end of the input
+func (p *exprParser) peek() rune {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// parseSum := product (('+' | '-') product)*
+func (p *exprParser) parseSum() (exprFunc, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == '+' {
+			left = func(vars map[string]float64) float64 { return l(vars) + right(vars) }
+		} else {
+			left = func(vars map[string]float64) float64 { return l(vars) - right(vars) }
+		}
+	}
+}
+
+// parseProduct := unary (('*' | '/') unary)*
+func (p *exprParser) parseProduct() (exprFunc, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == '*' {
+			left = func(vars map[string]float64) float64 { return l(vars) * right(vars) }
+		} else {
+			left = func(vars map[string]float64) float64 { return l(vars) / right(vars) }
+		}
+	}
+}
+
+// parseUnary := '-' unary | primary
+func (p *exprParser) parseUnary() (exprFunc, error) {
+	if p.peek() == '-' {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(vars map[string]float64) float64 { return -operand(vars) }, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary := number | name | '(' sum ')'
+func (p *exprParser) parsePrimary() (exprFunc, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		inner, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ')' at position %d", p.pos+1)
+		}
+		p.pos++
+		return inner, nil
+	case unicode.IsDigit(c) || c == '.':
+		start := p.pos
+		for p.pos < len(p.input) && (unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		value, err := strconv.ParseFloat(string(p.input[start:p.pos]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", string(p.input[start:p.pos]))
+		}
+		return func(map[string]float64) float64 { return value }, nil
+	case isNameRune(c, true):
+		start := p.pos
+		for p.pos < len(p.input) && isNameRune(p.input[p.pos], false) {
+			p.pos++
+		}
+		name := string(p.input[start:p.pos])
+		p.names[name] = struct{}{}
+		return func(vars map[string]float64) float64 { return vars[name] }, nil
+	default:
+		return nil, fmt.Errorf("unexpected '%c' at position %d", c, p.pos+1)
+	}
+}
+
+func isNameRune(c rune, first bool) bool {
+	return c == '_' || unicode.IsLetter(c) || (!first && unicode.IsDigit(c))
+}
+
+// isValidSeriesName reports whether name can be used as identifier in an expression
+func isValidSeriesName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !isNameRune(c, i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+/* =================================== EXPRESSION QUERY ======================================== */
+
+// loadExpressionTable loads every series used in the expression, aligns them on a common
+// time grid and evaluates the expression per point. The transforms of the query apply to the result.
+func (d *Datasource) loadExpressionTable(qm queryModel, timeRange backend.TimeRange) (*metricResult, error) {
+	evaluate, names, err := parseExpression(qm.Expression)
+	if err != nil {
+		d.metrics.IncError("invalid_expression")
+		return nil, &queryError{status: backend.StatusBadRequest, message: fmt.Sprintf("invalid expression: %v", err)}
+	}
+
+	declared := make(map[string]expressionSeries, len(qm.ExpressionSeries))
+	for _, series := range qm.ExpressionSeries {
+		if !isValidSeriesName(series.Name) {
+			return nil, &queryError{status: backend.StatusBadRequest, message: fmt.Sprintf("invalid series name '%s'", series.Name)}
+		}
+		declared[series.Name] = series
+	}
+
+	// Load the series in a stable order, the API caches historic data per sensor
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		if _, ok := declared[name]; !ok {
+			return nil, &queryError{status: backend.StatusBadRequest, message: fmt.Sprintf("unknown series '%s' in expression", name)}
+		}
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	tables := make(map[string]*metricTable, len(sortedNames))
+	notices := make([]data.Notice, 0)
+	var step time.Duration
+	for _, name := range sortedNames {
+		result, err := d.loadMetricTable(expressionSeriesQuery(qm, declared[name]), timeRange)
+		if err != nil {
+			return nil, fmt.Errorf("series %s: %w", name, err)
+		}
+		tables[name] = result.table
+		notices = append(notices, result.notices...)
+		if result.spacing > step {
+			step = result.spacing
+		}
+	}
+
+	times, values := alignSeries(tables, step)
+
+	resultName := qm.ExpressionName
+	if resultName == "" {
+		resultName = qm.Expression
+	}
+	column := &metricColumn{
+		Selection:   channelSelection{Name: resultName},
+		Values:      make([]*float64, len(times)),
+		Unit:        qm.ExpressionUnit,
+		Transformed: true,
+	}
+
+	vars := make(map[string]float64, len(sortedNames))
+	for i := range times {
+		complete := true
+		for _, name := range sortedNames {
+			value := values[name][i]
+			if value == nil {
+				complete = false
+				break
+			}
+			vars[name] = *value
+		}
+		if !complete {
+			continue
+		}
+		if result := evaluate(vars); !math.IsNaN(result) && !math.IsInf(result, 0) {
+			column.Values[i] = &result
+		}
+	}
+
+	table := &metricTable{Times: times, Columns: []*metricColumn{column}}
+	if err := table.applyTransforms(qm.Transforms); err != nil {
+		d.metrics.IncError("invalid_transform")
+		return nil, &queryError{status: backend.StatusBadRequest, message: err.Error()}
+	}
+
+	return &metricResult{
+		table:   table,
+		spacing: step,
+		notices: notices,
+	}, nil
+}
+
+// expressionSeriesQuery derives the query of one expression series from the metrics query
+func expressionSeriesQuery(qm queryModel, series expressionSeries) queryModel {
+	seriesQuery := qm
+	if series.SensorId != "" {
+		seriesQuery.SensorId = series.SensorId
+	}
+	seriesQuery.Channel = series.Channel
+	seriesQuery.ChannelArray = nil
+	seriesQuery.ChannelIds = nil
+	if series.ChannelId != "" {
+		seriesQuery.ChannelIds = []string{series.ChannelId}
+	}
+	if series.Channel != "" {
+		seriesQuery.ChannelArray = []string{series.Channel}
+	}
+	seriesQuery.Expression = ""
+	seriesQuery.Transforms = nil
+	return seriesQuery
+}
+
+// alignSeries puts the first column of every table onto a common grid of step wide buckets.
+// Several values in one bucket are averaged, buckets without values stay null.
+// A step of 0 aligns on the exact timestamps.
+func alignSeries(tables map[string]*metricTable, step time.Duration) ([]time.Time, map[string][]*float64) {
+	type accumulator struct {
+		sum   float64
+		count int
+	}
+	buckets := make(map[string]map[int64]*accumulator, len(tables))
+	keys := make(map[int64]time.Time)
+
+	for name, table := range tables {
+		buckets[name] = make(map[int64]*accumulator)
+		if len(table.Columns) == 0 {
+			continue
+		}
+		for i, ts := range table.Times {
+			if step > 0 {
+				ts = ts.Truncate(step)
+			}
+			key := ts.UnixNano()
+			keys[key] = ts
+
+			acc, ok := buckets[name][key]
+			if !ok {
+				acc = &accumulator{}
+				buckets[name][key] = acc
+			}
+			if value := table.Columns[0].Values[i]; value != nil {
+				acc.sum += *value
+				acc.count++
+			}
+		}
+	}
+
+	sortedKeys := make([]int64, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Slice(sortedKeys, func(i, j int) bool { return sortedKeys[i] < sortedKeys[j] })
+
+	times := make([]time.Time, len(sortedKeys))
+	for i, key := range sortedKeys {
+		times[i] = keys[key]
+	}
+
+	values := make(map[string][]*float64, len(tables))
+	for name := range tables {
+		column := make([]*float64, len(sortedKeys))
+		for i, key := range sortedKeys {
+			if acc, ok := buckets[name][key]; ok && acc.count > 0 {
+				mean := acc.sum / float64(acc.count)
+				column[i] = &mean
+			}
+		}
+		values[name] = column
+	}
+	return times, values
+}
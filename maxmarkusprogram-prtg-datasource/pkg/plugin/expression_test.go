@@ -0,0 +1,182 @@
+package plugin
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestParseExpression(t *testing.T) {
+	vars := map[string]float64{"a": 2, "b": 5, "in_2": 10}
+	tests := []struct {
+		expression string
+		want       float64
+		names      []string
+	}{
+		{"1 + 2 * 3", 7, nil},
+		{"(1 + 2) * 3", 9, nil},
+		{"8 - 3 - 2", 3, nil},
+		{"12 / 3 / 2", 2, nil},
+		{"-a + b", 3, []string{"a", "b"}},
+		{"a - -b", 7, []string{"a", "b"}},
+		{"--a", 2, []string{"a"}},
+		{"-(a + b) * 2", -14, []string{"a", "b"}},
+		{"a * b - in_2 / a", 5, []string{"a", "b", "in_2"}},
+		{" ( ( in_2 ) ) ", 10, []string{"in_2"}},
+		{"0.5 * .5", 0.25, nil},
+	}
+	for _, tt := range tests {
+		fn, names, err := parseExpression(tt.expression)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.expression, err)
+		}
+		if got := fn(vars); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%q = %v, want %v", tt.expression, got, tt.want)
+		}
+		got := make([]string, 0, len(names))
+		for name := range names {
+			got = append(got, name)
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(tt.names, ",") {
+			t.Errorf("%q uses %v, want %v", tt.expression, got, tt.names)
+		}
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		err        string
+	}{
+		{"", "unexpected end of expression"},
+		{"a +", "unexpected end of expression"},
+		{"(a + b", "missing ')' at position 7"},
+		{"a b", "unexpected 'b' at position 3"},
+		{"a + )", "unexpected ')' at position 5"},
+		{"1..2", "invalid number '1..2'"},
+		{"a $ b", "unexpected '$' at position 3"},
+		{"a * (b))", "unexpected ')' at position 8"},
+	}
+	for _, tt := range tests {
+		_, _, err := parseExpression(tt.expression)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%q: error = %v, want %q", tt.expression, err, tt.err)
+		}
+	}
+}
+
+func TestAlignSeries(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tables := map[string]*metricTable{
+		"a": {
+			Times:   []time.Time{start, start.Add(30 * time.Second), start.Add(time.Minute)},
+			Columns: []*metricColumn{{Values: []*float64{ptr(1), ptr(3), nil}}},
+		},
+		"b": {
+			Times:   []time.Time{start.Add(10 * time.Second), start.Add(2 * time.Minute)},
+			Columns: []*metricColumn{{Values: []*float64{ptr(5), ptr(7)}}},
+		},
+	}
+
+	times, values := alignSeries(tables, time.Minute)
+	wantTimes := []time.Time{start, start.Add(time.Minute), start.Add(2 * time.Minute)}
+	if len(times) != len(wantTimes) {
+		t.Fatalf("got %d buckets, want %d", len(times), len(wantTimes))
+	}
+	for i := range times {
+		if !times[i].Equal(wantTimes[i]) {
+			t.Errorf("bucket %d at %v, want %v", i, times[i], wantTimes[i])
+		}
+	}
+	if want := []*float64{ptr(2), nil, nil}; !equalValues(values["a"], want) {
+		t.Errorf("a = %v, want %v", formatValues(values["a"]), formatValues(want))
+	}
+	if want := []*float64{ptr(5), nil, ptr(7)}; !equalValues(values["b"], want) {
+		t.Errorf("b = %v, want %v", formatValues(values["b"]), formatValues(want))
+	}
+
+	// Without a step only equal timestamps are combined
+	times, values = alignSeries(tables, 0)
+	if len(times) != 5 || values["a"][1] != nil || values["b"][1] == nil {
+		t.Errorf("exact alignment: %d buckets, a %v, b %v", len(times), formatValues(values["a"]), formatValues(values["b"]))
+	}
+}
+
+// expressionTestAPI has the ping channel of sensor 1001 and a second sensor whose rows are
+// 20 seconds later, both are scanned once a minute
+func expressionTestAPI(values ...float64) *fakeAPI {
+	api := alertTestAPI()
+	api.sensors["1001"].IntervalRAW = 60
+	api.sensors["1002"] = &PrtgSensorListItemStruct{ObjectId: 1002, IntervalRAW: 60}
+	api.channels["1002"] = &PrtgChannelListResponse{Channels: []PrtgChannelListItemStruct{{ObjectId: 0, Name: "Load"}}}
+	rows := make([]PrtgValues, len(values))
+	for i, value := range values {
+		at := alertEnd.Add(time.Duration(i-len(values))*time.Minute + 20*time.Second)
+		rows[i] = PrtgValues{DatetimeRAW: oleDate(at), Value: map[string]interface{}{"value_0": "", "value_0_raw": value}}
+	}
+	api.historic["1002"] = &PrtgHistoricalDataResponse{HistData: rows}
+	return api
+}
+
+func TestExpressionQuery(t *testing.T) {
+	ds := newTestDatasource(t, expressionTestAPI(3, 0), nil)
+	timeRange := backend.TimeRange{From: alertEnd.Add(-time.Hour), To: alertEnd}
+	qm := queryModel{
+		QueryType:  "metrics",
+		SensorId:   "1001",
+		Expression: "ping / load",
+		ExpressionSeries: []expressionSeries{
+			{Name: "ping", ChannelId: "0"},
+			{Name: "load", SensorId: "1002", ChannelId: "0"},
+		},
+	}
+
+	result, err := ds.loadExpressionTable(qm, timeRange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The series are 20 seconds apart and meet in the minute buckets, 15 / 0 becomes null
+	if want := []*float64{ptr(4), nil}; !equalValues(result.table.Columns[0].Values, want) {
+		t.Errorf("values = %v, want %v", formatValues(result.table.Columns[0].Values), formatValues(want))
+	}
+	if result.table.Columns[0].Selection.Name != "ping / load" {
+		t.Errorf("name = %q, want the expression", result.table.Columns[0].Selection.Name)
+	}
+
+	// 0 / 0 is not a number and becomes null as well
+	qm.Expression = "(ping - ping) / load"
+	result, err = ds.loadExpressionTable(qm, timeRange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*float64{ptr(0), nil}; !equalValues(result.table.Columns[0].Values, want) {
+		t.Errorf("values = %v, want %v", formatValues(result.table.Columns[0].Values), formatValues(want))
+	}
+}
+
+func TestExpressionQueryErrors(t *testing.T) {
+	ds := newTestDatasource(t, expressionTestAPI(1), nil)
+	timeRange := backend.TimeRange{From: alertEnd.Add(-time.Hour), To: alertEnd}
+	series := []expressionSeries{{Name: "ping", ChannelId: "0"}}
+	tests := []struct {
+		expression string
+		series     []expressionSeries
+		err        string
+	}{
+		{"ping + load", series, "unknown series 'load' in expression"},
+		{"ping +", series, "invalid expression: unexpected end of expression"},
+		{"ping", []expressionSeries{{Name: "1ping", ChannelId: "0"}}, "invalid series name '1ping'"},
+	}
+	for _, tt := range tests {
+		qm := queryModel{QueryType: "metrics", SensorId: "1001", Expression: tt.expression, ExpressionSeries: tt.series}
+		_, err := ds.loadExpressionTable(qm, timeRange)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%q: error = %v, want %q", tt.expression, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/1DeliDolu/PRTG/maxmarkusprogram-prtg-datasource/pkg/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// fakeAPI serves canned PRTG responses. Methods without data are inherited from the nil
+// PRTGAPI and panic, so a test notices requests it did not expect.
+type fakeAPI struct {
+	PRTGAPI
+
+	mu          sync.Mutex
+	status      *PrtgStatusListResponse
+	statusErr   error
+	statusCalls int
+	sensors     map[string]*PrtgSensorListItemStruct
+	channels    map[string]*PrtgChannelListResponse
+	historic    map[string]*PrtgHistoricalDataResponse
+	// deviceSensors are the sensor lists of GetSensors by device
+	deviceSensors map[string]*PrtgSensorsListResponse
+	// tables returns the rows of a table.json request
+	tables func(content string, params map[string]string) []map[string]interface{}
+	// tableRequests records the parameters of every table.json request
+	tableRequests []map[string]string
+}
+
+func (f *fakeAPI) GetStatusList() (*PrtgStatusListResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statusCalls++
+	if f.statusErr != nil {
+		return nil, f.statusErr
+	}
+	return f.status, nil
+}
+
+func (f *fakeAPI) GetSensorDetails(sensorId string) (*PrtgSensorListItemStruct, error) {
+	if sensor, ok := f.sensors[sensorId]; ok {
+		return sensor, nil
+	}
+	return nil, errNotFound(sensorId)
+}
+
+func (f *fakeAPI) GetSensors(device string) (*PrtgSensorsListResponse, error) {
+	if sensors, ok := f.deviceSensors[device]; ok {
+		return sensors, nil
+	}
+	return nil, errNotFound(device)
+}
+
+func (f *fakeAPI) GetChannels(sensorId string) (*PrtgChannelListResponse, error) {
+	if channels, ok := f.channels[sensorId]; ok {
+		return channels, nil
+	}
+	return nil, errNotFound(sensorId)
+}
+
+func (f *fakeAPI) GetTable(content string, params map[string]string) ([]map[string]interface{}, error) {
+	f.mu.Lock()
+	f.tableRequests = append(f.tableRequests, params)
+	f.mu.Unlock()
+	if f.tables == nil {
+		return nil, nil
+	}
+	return f.tables(content, params), nil
+}
+
+func (f *fakeAPI) GetLiveChannels(sensorId string) (*PrtgChannelListResponse, error) {
+	return f.GetChannels(sensorId)
+}
+
+func (f *fakeAPI) GetLiveTable(content string, params map[string]string) ([]map[string]interface{}, error) {
+	return f.GetTable(content, params)
+}
+
+func (f *fakeAPI) GetLookup(lookupId string) (*PrtgValueLookup, error) {
+	return nil, errNotFound(lookupId)
+}
+
+func (f *fakeAPI) GetHistoricalData(sensorId string, from, to time.Time, loc *time.Location) (*PrtgHistoricalDataResponse, error) {
+	if data, ok := f.historic[sensorId]; ok {
+		return data, nil
+	}
+	return nil, errNotFound(sensorId)
+}
+
+func (f *fakeAPI) GetCacheTime() time.Duration { return time.Minute }
+
+type errNotFound string
+
+func (e errNotFound) Error() string { return "object " + string(e) + " not found" }
+
+// newTestDatasource returns a datasource on the fake API, settings may be nil
+func newTestDatasource(t *testing.T, api PRTGAPI, settings *models.PluginSettings) *Datasource {
+	t.Helper()
+	if settings == nil {
+		settings = &models.PluginSettings{Timezone: "UTC"}
+	}
+	logger := NewLogger()
+	ds := &Datasource{
+		api:        api,
+		logger:     logger,
+		tracer:     NewTracer(logger),
+		metrics:    NewMetrics(prometheus.NewRegistry()),
+		queryCache: make(map[string]*QueryCacheEntry),
+		cacheTime:  time.Minute,
+		settings:   settings,
+		loc:        loadTimezone(settings.Timezone),
+		streamManager: &streamManager{
+			streams:       make(map[string]*activeStream),
+			activeStreams: make(map[string]map[string]*activeStream),
+		},
+	}
+	ds.mux = ds.newQueryTypeMux()
+	return ds
+}
@@ -0,0 +1,217 @@
+package plugin
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+/* =================================== UNIT MAPPING ============================================ */
+
+// prtgUnitMap maps the unit suffix PRTG shows for a channel to the Grafana unit id.
+// Values in historic data are already scaled to the displayed unit, so e.g.
+// "Mbit/s" has to become "Mbits" and not the base unit "bps".
+var prtgUnitMap = map[string]string{
+	"%":       "percent",
+	"#":       "short",
+	"msec":    "ms",
+	"ms":      "ms",
+	"sec":     "s",
+	"s":       "s",
+	"min":     "m",
+	"h":       "h",
+	"d":       "d",
+	"byte":    "decbytes",
+	"bytes":   "decbytes",
+	"kbyte":   "deckbytes",
+	"mbyte":   "decmbytes",
+	"gbyte":   "decgbytes",
+	"tbyte":   "dectbytes",
+	"byte/s":  "Bps",
+	"kbyte/s": "KBs",
+	"mbyte/s": "MBs",
+	"gbyte/s": "GBs",
+	"bit/s":   "bps",
+	"kbit/s":  "Kbits",
+	"mbit/s":  "Mbits",
+	"gbit/s":  "Gbits",
+	"°c":      "celsius",
+	"°f":      "fahrenheit",
+	"dbm":     "dBm",
+	"db":      "dB",
+	"v":       "volt",
+	"mv":      "mvolt",
+	"a":       "amp",
+	"ma":      "mamp",
+	"w":       "watt",
+	"kw":      "kwatt",
+	"hz":      "hertz",
+	"khz":     "khertz",
+	"mhz":     "mhertz",
+	"ghz":     "ghertz",
+	"rpm":     "rotrpm",
+	"/s":      "cps",
+	"#/s":     "cps",
+	"#/min":   "cpm",
+}
+
+// grafanaUnitFromPRTG converts a PRTG channel unit into a Grafana unit id.
+// Unknown units are passed through as a custom suffix so they still show up.
+func grafanaUnitFromPRTG(unit string) string {
+	unit = strings.TrimSpace(unit)
+	if unit == "" {
+		return ""
+	}
+	if mapped, ok := prtgUnitMap[strings.ToLower(unit)]; ok {
+		return mapped
+	}
+	return "suffix: " + unit
+}
+
+/* =================================== FIELD CONFIG ============================================ */
+
+// applyChannelFieldConfig sets unit, min/max and thresholds of a field from the PRTG channel settings.
+// Localized limits are parsed with the decimal separator of the PRTG server.
+func applyChannelFieldConfig(config *data.FieldConfig, channel *PrtgChannelListItemStruct, decimal string) {
+	if config == nil || channel == nil {
+		return
+	}
+
+	config.Unit = grafanaUnitFromPRTG(channel.Unit)
+	if config.Unit == "percent" {
+		config.SetMin(0)
+		config.SetMax(100)
+	}
+
+	if thresholds := thresholdsFromLimits(channel, decimal); thresholds != nil {
+		config.Thresholds = thresholds
+		if config.Custom == nil {
+			config.Custom = map[string]interface{}{}
+		}
+		config.Custom["thresholdsStyle"] = map[string]interface{}{"mode": "line"}
+	}
+}
+
+// thresholdsFromLimits translates the PRTG lower/upper warning and error limits
+// into ascending Grafana threshold steps. Returns nil if no limit is configured.
+func thresholdsFromLimits(channel *PrtgChannelListItemStruct, decimal string) *data.ThresholdsConfig {
+	minError, hasMinError := channel.LimitMinError.Float(decimal)
+	minWarning, hasMinWarning := channel.LimitMinWarning.Float(decimal)
+	maxWarning, hasMaxWarning := channel.LimitMaxWarning.Float(decimal)
+	maxError, hasMaxError := channel.LimitMaxError.Float(decimal)
+	if !hasMinError && !hasMinWarning && !hasMaxWarning && !hasMaxError {
+		return nil
+	}
+
+	// The base step covers everything below the lowest configured limit
+	baseColor := "green"
+	switch {
+	case hasMinError:
+		baseColor = "red"
+	case hasMinWarning:
+		baseColor = "orange"
+	}
+	steps := []data.Threshold{{Color: baseColor}}
+
+	if hasMinError {
+		color := "green"
+		if hasMinWarning {
+			color = "orange"
+		}
+		steps = append(steps, data.NewThreshold(minError, color, ""))
+	}
+	if hasMinWarning {
+		steps = append(steps, data.NewThreshold(minWarning, "green", ""))
+	}
+	if hasMaxWarning {
+		steps = append(steps, data.NewThreshold(maxWarning, "orange", ""))
+	}
+	if hasMaxError {
+		steps = append(steps, data.NewThreshold(maxError, "red", ""))
+	}
+
+	return &data.ThresholdsConfig{
+		Mode:  data.ThresholdsModeAbsolute,
+		Steps: steps,
+	}
+}
+
+/* =================================== VALUE MAPPINGS ========================================== */
+
+// lookupStateColor maps the state of a PRTG lookup entry to a Grafana color.
+func lookupStateColor(state string) string {
+	switch strings.ToLower(state) {
+	case "ok":
+		return "green"
+	case "warning":
+		return "orange"
+	case "error":
+		return "red"
+	default:
+		// "None" has no color in PRTG either
+		return ""
+	}
+}
+
+// valueMappingsFromLookup converts a PRTG value lookup into Grafana value mappings.
+func valueMappingsFromLookup(lookup *PrtgValueLookup) data.ValueMappings {
+	if lookup == nil {
+		return nil
+	}
+
+	mappings := data.ValueMappings{}
+	index := 0
+
+	if len(lookup.SingleInts) > 0 {
+		mapper := data.ValueMapper{}
+		for _, single := range lookup.SingleInts {
+			mapper[strconv.FormatFloat(single.Value, 'f', -1, 64)] = data.ValueMappingResult{
+				Text:  strings.TrimSpace(single.Text),
+				Color: lookupStateColor(single.State),
+				Index: index,
+			}
+			index++
+		}
+		mappings = append(mappings, mapper)
+	}
+
+	for _, r := range lookup.Ranges {
+		from := data.ConfFloat64(r.From)
+		to := data.ConfFloat64(r.To)
+		mappings = append(mappings, data.RangeValueMapper{
+			From: &from,
+			To:   &to,
+			Result: data.ValueMappingResult{
+				Text:  strings.TrimSpace(r.Text),
+				Color: lookupStateColor(r.State),
+				Index: index,
+			},
+		})
+		index++
+	}
+
+	if len(mappings) == 0 {
+		return nil
+	}
+	return mappings
+}
+
+// channelValueMappings fetches the lookup of a channel and returns it as value mappings.
+// Channels without lookup or lookups that cannot be loaded yield no mappings.
+func (d *Datasource) channelValueMappings(channel *PrtgChannelListItemStruct) data.ValueMappings {
+	if channel == nil || channel.ValueLookup == "" {
+		return nil
+	}
+
+	lookup, err := d.api.GetLookup(channel.ValueLookup)
+	if err != nil {
+		d.logger.Warn("Failed to fetch value lookup",
+			"error", err,
+			"lookup", channel.ValueLookup,
+			"channel", channel.Name,
+		)
+		return nil
+	}
+	return valueMappingsFromLookup(lookup)
+}
@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	// historicRowLimit is the count sent with every historicdata.json request.
+	// A chunk returning that many rows was cut off by PRTG.
+	historicRowLimit = 50000
+	// historicChunkRows is the number of rows a chunk is sized for, well below historicRowLimit
+	historicChunkRows = 20000
+	// maxHistoricChunkSpan keeps single requests within the range PRTG accepts for averaged data
+	maxHistoricChunkSpan = 365 * 24 * time.Hour
+	// maxConcurrentChunks limits the parallel historicdata.json requests of one query
+	maxConcurrentChunks = 4
+)
+
+/* =================================== HISTORIC CHUNKS ========================================= */
+
+// historicChunk is one part of a historic data request, from and to are in the PRTG timezone
+type historicChunk struct {
+	from time.Time
+	to   time.Time
+}
+
+// historicChunkSpan returns the time range one chunk covers for the given averaging.
+// Raw data (avg 0) is sized for one value per second, the shortest PRTG scanning interval.
+func historicChunkSpan(avg time.Duration) time.Duration {
+	step := avg
+	if step <= 0 {
+		step = time.Second
+	}
+	span := step * historicChunkRows
+	if span > maxHistoricChunkSpan {
+		span = maxHistoricChunkSpan
+	}
+	return span
+}
+
+// splitHistoricRange splits [from, to] into consecutive chunks of at most span.
+// Neighbouring chunks share their boundary, duplicate rows are removed when merging.
+func splitHistoricRange(from, to time.Time, span time.Duration) []historicChunk {
+	chunks := make([]historicChunk, 0, int(to.Sub(from)/span)+1)
+	for start := from; start.Before(to); start = start.Add(span) {
+		end := start.Add(span)
+		if end.After(to) {
+			end = to
+		}
+		chunks = append(chunks, historicChunk{from: start, to: end})
+	}
+	if len(chunks) == 0 {
+		chunks = append(chunks, historicChunk{from: from, to: to})
+	}
+	return chunks
+}
+
+// fetchHistoricChunks fetches all chunks of a historic range with bounded concurrency and
+// merges them in chronological order. Chunks hitting the row limit are counted in TruncatedChunks.
+func (a *Api) fetchHistoricChunks(sensorID string, from, to time.Time, avg time.Duration) (*PrtgHistoricalDataResponse, error) {
+	chunks := splitHistoricRange(from, to, historicChunkSpan(avg))
+	if len(chunks) > 1 {
+		log.DefaultLogger.Debug("Splitting historical data request",
+			"sensorID", sensorID,
+			"chunks", len(chunks),
+			"avg", avg,
+		)
+	}
+
+	results := make([]*PrtgHistoricalDataResponse, len(chunks))
+	errs := make([]error, len(chunks))
+	semaphore := make(chan struct{}, maxConcurrentChunks)
+	var wg sync.WaitGroup
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i], errs[i] = a.getHistoricChunk(sensorID, chunk.from, chunk.to, avg)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+	}
+
+	return mergeHistoricChunks(results), nil
+}
+
+// mergeHistoricChunks concatenates chunk responses and drops rows repeated at chunk boundaries
+func mergeHistoricChunks(results []*PrtgHistoricalDataResponse) *PrtgHistoricalDataResponse {
+	merged := &PrtgHistoricalDataResponse{
+		HistData: make([]PrtgValues, 0),
+	}
+	seen := make(map[string]struct{})
+
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		if merged.PrtgVersion == "" {
+			merged.PrtgVersion = result.PrtgVersion
+		}
+		if len(result.HistData) >= historicRowLimit {
+			merged.TruncatedChunks++
+		}
+
+		for _, row := range result.HistData {
+			key := row.Datetime
+			if row.DatetimeRAW > 0 {
+				key = fmt.Sprintf("%.8f", row.DatetimeRAW)
+			}
+			if _, duplicate := seen[key]; duplicate {
+				continue
+			}
+			seen[key] = struct{}{}
+			merged.HistData = append(merged.HistData, row)
+		}
+	}
+	merged.TreeSize = int64(len(merged.HistData))
+
+	return merged
+}
+
+// truncatedHistoricNotice warns that PRTG cut off some chunks at the row limit
+func truncatedHistoricNotice(historicalData *PrtgHistoricalDataResponse) *data.Notice {
+	if historicalData == nil || historicalData.TruncatedChunks == 0 {
+		return nil
+	}
+	return &data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text: fmt.Sprintf("%d historic data requests returned the maximum of %d rows, the data may be incomplete",
+			historicalData.TruncatedChunks, historicRowLimit),
+	}
+}
@@ -0,0 +1,164 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSplitHistoricRange(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tests := []struct {
+		name   string
+		avg    time.Duration
+		length time.Duration
+		span   time.Duration
+		chunks int
+	}{
+		{"raw data", 0, 12 * time.Hour, historicChunkRows * time.Second, 3},
+		{"raw data within one chunk", 0, time.Hour, historicChunkRows * time.Second, 1},
+		{"one minute average", time.Minute, 30 * day, historicChunkRows * time.Minute, 3},
+		{"one hour average is capped", time.Hour, 800 * day, maxHistoricChunkSpan, 3},
+		{"one day average is capped", 24 * time.Hour, 365 * day, maxHistoricChunkSpan, 1},
+		{"empty range", time.Minute, 0, historicChunkRows * time.Minute, 1},
+	}
+	for _, tt := range tests {
+		span := historicChunkSpan(tt.avg)
+		if span != tt.span {
+			t.Errorf("%s: span = %v, want %v", tt.name, span, tt.span)
+		}
+		to := from.Add(tt.length)
+		chunks := splitHistoricRange(from, to, span)
+		if len(chunks) != tt.chunks {
+			t.Fatalf("%s: got %d chunks, want %d", tt.name, len(chunks), tt.chunks)
+		}
+		if !chunks[0].from.Equal(from) || !chunks[len(chunks)-1].to.Equal(to) {
+			t.Errorf("%s: chunks cover %v - %v, want %v - %v", tt.name, chunks[0].from, chunks[len(chunks)-1].to, from, to)
+		}
+		for i, chunk := range chunks {
+			if chunk.to.Sub(chunk.from) > span {
+				t.Errorf("%s: chunk %d spans %v, more than %v", tt.name, i, chunk.to.Sub(chunk.from), span)
+			}
+			if i > 0 && !chunk.from.Equal(chunks[i-1].to) {
+				t.Errorf("%s: chunk %d starts at %v, the previous ends at %v", tt.name, i, chunk.from, chunks[i-1].to)
+			}
+		}
+	}
+}
+
+// historicRows returns rows at the given OLE dates
+func historicRows(dates ...float64) []PrtgValues {
+	rows := make([]PrtgValues, len(dates))
+	for i, date := range dates {
+		rows[i] = PrtgValues{DatetimeRAW: date, Value: map[string]interface{}{"value_0_raw": date}}
+	}
+	return rows
+}
+
+func TestMergeHistoricChunks(t *testing.T) {
+	merged := mergeHistoricChunks([]*PrtgHistoricalDataResponse{
+		{PrtgVersion: "24.1", HistData: historicRows(1, 2, 3)},
+		nil,
+		{HistData: historicRows(3, 4)},
+		{HistData: historicRows(4, 5)},
+	})
+	if len(merged.HistData) != 5 || merged.TreeSize != 5 {
+		t.Fatalf("got %d rows (treesize %d), want the 5 distinct rows", len(merged.HistData), merged.TreeSize)
+	}
+	for i, row := range merged.HistData {
+		if row.DatetimeRAW != float64(i+1) {
+			t.Errorf("row %d at %v, want chronological order", i, row.DatetimeRAW)
+		}
+	}
+	if merged.PrtgVersion != "24.1" || merged.TruncatedChunks != 0 {
+		t.Errorf("version %q, truncated %d", merged.PrtgVersion, merged.TruncatedChunks)
+	}
+	if notice := truncatedHistoricNotice(merged); notice != nil {
+		t.Errorf("unexpected notice %v", notice)
+	}
+
+	// Rows without raw date are told apart by their formatted date
+	merged = mergeHistoricChunks([]*PrtgHistoricalDataResponse{
+		{HistData: []PrtgValues{{Datetime: "01.01.2026 00:00:00"}}},
+		{HistData: []PrtgValues{{Datetime: "01.01.2026 00:00:00"}, {Datetime: "01.01.2026 00:01:00"}}},
+	})
+	if len(merged.HistData) != 2 {
+		t.Errorf("got %d rows, want 2", len(merged.HistData))
+	}
+}
+
+func TestMergeTruncatedHistoricChunk(t *testing.T) {
+	dates := make([]float64, historicRowLimit)
+	for i := range dates {
+		dates[i] = float64(i + 1)
+	}
+	merged := mergeHistoricChunks([]*PrtgHistoricalDataResponse{
+		{HistData: historicRows(dates...)},
+		{HistData: historicRows(historicRowLimit, historicRowLimit+1)},
+	})
+	if merged.TruncatedChunks != 1 {
+		t.Errorf("truncated chunks = %d, want 1", merged.TruncatedChunks)
+	}
+	if len(merged.HistData) != historicRowLimit+1 {
+		t.Errorf("got %d rows, want %d", len(merged.HistData), historicRowLimit+1)
+	}
+	notice := truncatedHistoricNotice(merged)
+	if notice == nil || notice.Text != "1 historic data requests returned the maximum of 50000 rows, the data may be incomplete" {
+		t.Errorf("notice = %v", notice)
+	}
+}
+
+func TestFetchHistoricChunks(t *testing.T) {
+	const format = "2006-01-02-15-04-05"
+	var requests, running, maxRunning atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if n := running.Add(1); n > maxRunning.Load() {
+			maxRunning.Store(n)
+		}
+		defer running.Add(-1)
+		time.Sleep(10 * time.Millisecond)
+
+		// Every chunk returns a row at both boundaries
+		rows := make([]PrtgValues, 0, 2)
+		for _, param := range []string{"sdate", "edate"} {
+			date, err := time.Parse(format, r.URL.Query().Get(param))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			rows = append(rows, historicRows(oleDate(date))...)
+		}
+		_ = json.NewEncoder(w).Encode(PrtgHistoricalDataResponse{HistData: rows})
+	}))
+	defer server.Close()
+
+	api := NewApi(server.URL, "token", time.Minute, 5*time.Second)
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	span := historicChunkSpan(time.Minute)
+	to := from.Add(10 * span)
+
+	merged, err := api.fetchHistoricChunks("1001", from, to, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := requests.Load(); got != 10 {
+		t.Errorf("made %d requests, want 10", got)
+	}
+	if got := maxRunning.Load(); got > maxConcurrentChunks {
+		t.Errorf("%d requests ran in parallel, want at most %d", got, maxConcurrentChunks)
+	}
+	// 10 chunks share 9 boundaries
+	if len(merged.HistData) != 11 {
+		t.Fatalf("got %d rows, want 11", len(merged.HistData))
+	}
+	for i := 1; i < len(merged.HistData); i++ {
+		if merged.HistData[i].DatetimeRAW <= merged.HistData[i-1].DatetimeRAW {
+			t.Fatalf("row %d is not after row %d", i, i-1)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package plugin
+
+import (
+	"strconv"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+/* =================================== SERIES LABELS =========================================== */
+
+// Label keys identifying a PRTG series. Legends can use them as ${__field.labels.sensor} etc.
+const (
+	LabelProbe     = "probe"
+	LabelGroup     = "group"
+	LabelDevice    = "device"
+	LabelSensor    = "sensor"
+	LabelSensorID  = "sensorId"
+	LabelChannel   = "channel"
+	LabelChannelID = "channelId"
+	LabelTags      = "tags"
+)
+
+// labelsFromSensor returns the labels of a sensor from its table row, empty values are left out
+func labelsFromSensor(sensor *PrtgSensorListItemStruct) data.Labels {
+	labels := data.Labels{}
+	setLabel(labels, LabelProbe, sensor.Probe)
+	setLabel(labels, LabelGroup, sensor.Group)
+	setLabel(labels, LabelDevice, sensor.Device)
+	setLabel(labels, LabelSensor, sensor.Sensor)
+	setLabel(labels, LabelTags, sensor.Tags)
+	if sensor.ObjectId > 0 {
+		labels[LabelSensorID] = strconv.FormatInt(sensor.ObjectId, 10)
+	}
+	return labels
+}
+
+// queryLabels returns the labels of the objects selected in a query. The sensor table
+// provides probe and tags; if it cannot be loaded the names stored in the query are used.
+func (d *Datasource) queryLabels(qm queryModel) data.Labels {
+	if qm.SensorId != "" {
+		sensor, err := d.api.GetSensorDetails(qm.SensorId)
+		if err == nil {
+			return labelsFromSensor(sensor)
+		}
+		d.logger.Debug("Failed to fetch sensor details for labels", "error", err, "sensorId", qm.SensorId)
+	}
+
+	labels := data.Labels{}
+	setLabel(labels, LabelGroup, qm.Group)
+	setLabel(labels, LabelDevice, qm.Device)
+	setLabel(labels, LabelSensor, qm.Sensor)
+	setLabel(labels, LabelSensorID, qm.SensorId)
+	return labels
+}
+
+// propertyLabels returns the labels of the object a property query reads
+func (d *Datasource) propertyLabels(qm queryModel, property string) data.Labels {
+	labels := data.Labels{}
+	switch property {
+	case "sensor":
+		return d.queryLabels(qm)
+	case "device":
+		setLabel(labels, LabelDevice, qm.Device)
+		fallthrough
+	case "group":
+		setLabel(labels, LabelGroup, qm.Group)
+	}
+	return labels
+}
+
+// channelLabels extends the object labels by the channel of a column
+func channelLabels(labels data.Labels, sel channelSelection) data.Labels {
+	result := labels.Copy()
+	if result == nil {
+		result = data.Labels{}
+	}
+	setLabel(result, LabelChannel, sel.Name)
+	setLabel(result, LabelChannelID, sel.ID)
+	return result
+}
+
+func setLabel(labels data.Labels, key, value string) {
+	if value != "" {
+		labels[key] = value
+	}
+}
+
+// legacyDisplayName builds the display name of the Include* options. It is kept for existing
+// panels and set as DisplayNameFromDS, so panel display name settings still take precedence.
+// Without any Include* option it returns "" and Grafana names the series from the labels.
+func legacyDisplayName(qm queryModel, name string) string {
+	if !qm.IncludeGroupName && !qm.IncludeDeviceName && !qm.IncludeSensorName {
+		return ""
+	}
+	displayName := name
+	if qm.IncludeGroupName && qm.Group != "" {
+		displayName = qm.Group + " - " + displayName
+	}
+	if qm.IncludeDeviceName && qm.Device != "" {
+		displayName = qm.Device + " - " + displayName
+	}
+	if qm.IncludeSensorName && qm.Sensor != "" {
+		displayName = qm.Sensor + " - " + displayName
+	}
+	return displayName
+}
@@ -0,0 +1,20 @@
+package plugin
+
+import "testing"
+
+func TestLegacyDisplayName(t *testing.T) {
+	qm := queryModel{Group: "Linux", Device: "web01", Sensor: "CPU"}
+	if got := legacyDisplayName(qm, "Total"); got != "" {
+		t.Errorf("legacyDisplayName without Include* options = %q; want empty", got)
+	}
+
+	qm.IncludeDeviceName = true
+	if got := legacyDisplayName(qm, "Total"); got != "web01 - Total" {
+		t.Errorf("legacyDisplayName with device name = %q", got)
+	}
+
+	qm.IncludeSensorName = true
+	if got := legacyDisplayName(qm, "Total"); got != "CPU - web01 - Total" {
+		t.Errorf("legacyDisplayName with device and sensor name = %q", got)
+	}
+}
@@ -0,0 +1,280 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// lastValueColumns are the sensor columns of a last value query
+const lastValueColumns = sensorTableColumns + ",lastvalue,lastcheck"
+
+// lastValueRow is one row of a last value query, a sensor or one of its channels
+type lastValueRow struct {
+	sensor  PrtgSensorListItemStruct
+	channel string
+	value   *float64
+	unit    string
+	checked *time.Time
+}
+
+/* =================================== SENSOR SELECTION ======================================== */
+
+// lastValueSensors loads the sensors of a last value query with their primary channel values:
+// the sensor ids, all sensors of the device or group ids, or all sensors if none is selected.
+// Search filters and ad-hoc filters restrict the list in each case.
+func (d *Datasource) lastValueSensors(qm queryModel) ([]map[string]interface{}, error) {
+	adhoc, err := newAdhocMatchers(qm.AdhocFilters)
+	if err != nil {
+		return nil, err
+	}
+	var matcher *sensorMatcher
+	if qm.GroupFilter != "" || qm.DeviceFilter != "" || qm.SensorFilter != "" || qm.SensorType != "" || len(qm.Tags) > 0 {
+		if matcher, err = newSensorMatcher(qm); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sensor ids are filtered here, PRTG only accepts one filter_objid per request
+	sensorIds := make(map[string]struct{})
+	for _, id := range qm.sensorIds() {
+		sensorIds[id] = struct{}{}
+	}
+	parentIds := []string{""}
+	if len(sensorIds) == 0 {
+		if ids := qm.deviceIds(); len(ids) > 0 {
+			parentIds = ids
+		} else if ids := qm.groupIds(); len(ids) > 0 {
+			parentIds = ids
+		}
+	}
+
+	seen := make(map[string]struct{})
+	result := make([]map[string]interface{}, 0)
+	for _, parentId := range parentIds {
+		params := map[string]string{"columns": lastValueColumns}
+		if parentId != "" {
+			params["id"] = parentId
+		}
+		if len(sensorIds) == 1 {
+			params["filter_objid"] = qm.sensorIds()[0]
+		}
+		remaining, _ := applyAdhocParams(adhoc, lastValueColumns, params)
+
+		rows, err := d.api.GetLiveTable("sensors", params)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			objid := rowString(row, "objid")
+			if _, ok := seen[objid]; ok || objid == "" {
+				continue
+			}
+			if _, ok := sensorIds[objid]; len(sensorIds) > 0 && !ok {
+				continue
+			}
+			if !matchesAdhoc(remaining, row) || (matcher != nil && !matcher.matches(sensorFromRow(row))) {
+				continue
+			}
+			seen[objid] = struct{}{}
+			result = append(result, row)
+		}
+	}
+	return result, nil
+}
+
+/* =================================== LAST VALUE QUERY ======================================== */
+
+// handleLastValueQuery returns the current value of the selected sensors as table, one row per
+// sensor or, if channels are selected, per sensor and channel. Without channel selection all
+// values come from one table.json request, so the query is cheap enough for short refresh intervals.
+func (d *Datasource) handleLastValueQuery(ctx context.Context, qm queryModel, frameName string) backend.DataResponse {
+	_, span := d.tracer.StartSpan(ctx, "handleLastValueQuery")
+	defer span.End()
+
+	loc, err := d.queryLocation(qm.Timezone)
+	if err != nil {
+		d.metrics.IncError("invalid_timezone")
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	sensorRows, err := d.lastValueSensors(qm)
+	if err != nil {
+		d.logger.Error("Last value query failed", "error", err)
+		recordError(span, err, "Last value query failed")
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	sortLastValueSensors(sensorRows)
+
+	notices := make([]data.Notice, 0)
+	var rows []lastValueRow
+	if len(qm.ChannelIds) == 0 && len(qm.ChannelArray) == 0 && qm.Channel == "" {
+		if qm.Limit > 0 && int64(len(sensorRows)) > qm.Limit {
+			sensorRows = sensorRows[:qm.Limit]
+		}
+		rows = make([]lastValueRow, 0, len(sensorRows))
+		for _, row := range sensorRows {
+			value := lastValueRow{
+				sensor:  sensorFromRow(row),
+				unit:    unitFromFormattedValue(rowString(row, "lastvalue")),
+				checked: rowTime(row, "lastcheck_raw", loc),
+			}
+			if f, ok := rowFloat(row, "lastvalue_raw"); ok {
+				value.value = &f
+			}
+			rows = append(rows, value)
+		}
+	} else {
+		rows, notices, err = d.lastChannelValues(qm, sensorRows, loc)
+		if err != nil {
+			d.logger.Error("Last value query failed", "error", err)
+			recordError(span, err, "Last value query failed")
+			return backend.ErrDataResponse(backend.StatusInternal, err.Error())
+		}
+	}
+
+	frame := lastValueFrame(frameName, rows)
+	frame.Meta = &data.FrameMeta{
+		Type: data.FrameTypeTable,
+		Custom: map[string]interface{}{
+			"queryType": "lastvalue",
+			"rows":      len(rows),
+		},
+	}
+	frame.AppendNotices(notices...)
+	return backend.DataResponse{Frames: []*data.Frame{frame}}
+}
+
+// lastChannelValues loads the channel tables of the sensors and returns the selected channels.
+// Every sensor needs a request, the sensors are therefore limited like a sensor search.
+func (d *Datasource) lastChannelValues(qm queryModel, sensorRows []map[string]interface{}, loc *time.Location) ([]lastValueRow, []data.Notice, error) {
+	maxSeries := qm.MaxSeries
+	if maxSeries <= 0 {
+		maxSeries = defaultMaxSeries
+	}
+	notices := make([]data.Notice, 0)
+	if len(sensorRows) > maxSeries {
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("%d sensors are selected, only the first %d are shown", len(sensorRows), maxSeries),
+		})
+		sensorRows = sensorRows[:maxSeries]
+	}
+
+	sensors := make([]PrtgSensorListItemStruct, len(sensorRows))
+	for i, row := range sensorRows {
+		sensors[i] = sensorFromRow(row)
+	}
+	results := make([][]lastValueRow, len(sensorRows))
+	errs := loadSensors(len(sensorRows), func(i int) error {
+		sensorQuery := qm
+		sensorQuery.SensorId = strconv.FormatInt(sensors[i].ObjectId, 10)
+		channels, err := d.api.GetLiveChannels(sensorQuery.SensorId)
+		if err != nil {
+			return err
+		}
+		selections, err := resolveChannelSelections(sensorQuery, channels)
+		if err != nil {
+			return err
+		}
+		checked := rowTime(sensorRows[i], "lastcheck_raw", loc)
+		for _, sel := range selections {
+			if sel.Info == nil {
+				continue
+			}
+			value := lastValueRow{sensor: sensors[i], channel: sel.Name, unit: sel.Info.Unit, checked: checked}
+			if f, ok := sel.Info.LastvalueRAW.Float(d.decimalSeparator); ok {
+				value.value = &f
+			}
+			results[i] = append(results[i], value)
+		}
+		if len(results[i]) == 0 {
+			names := make([]string, len(selections))
+			for j, sel := range selections {
+				names[j] = sel.Name
+			}
+			return &channelNotFoundError{channel: strings.Join(names, ", "), sensorId: sensorQuery.SensorId}
+		}
+		return nil
+	})
+	errNotices, err := d.sensorErrorNotices(sensors, errs)
+	if err != nil {
+		return nil, nil, err
+	}
+	notices = append(notices, errNotices...)
+
+	rows := make([]lastValueRow, 0, len(sensorRows))
+	for i := range sensorRows {
+		rows = append(rows, results[i]...)
+	}
+	return rows, notices, nil
+}
+
+// lastValueFrame builds the table frame; the value field gets the unit if all rows share it
+func lastValueFrame(frameName string, rows []lastValueRow) *data.Frame {
+	times := make([]*time.Time, len(rows))
+	sensorIds := make([]int64, len(rows))
+	groups := make([]string, len(rows))
+	devices := make([]string, len(rows))
+	sensors := make([]string, len(rows))
+	channels := make([]string, len(rows))
+	values := make([]*float64, len(rows))
+	units := make([]string, len(rows))
+	statuses := make([]string, len(rows))
+
+	commonUnit := ""
+	for i, row := range rows {
+		times[i] = row.checked
+		sensorIds[i] = row.sensor.ObjectId
+		groups[i] = row.sensor.Group
+		devices[i] = row.sensor.Device
+		sensors[i] = row.sensor.Sensor
+		channels[i] = row.channel
+		values[i] = row.value
+		units[i] = row.unit
+		statuses[i] = row.sensor.Status
+		if i == 0 {
+			commonUnit = row.unit
+		} else if commonUnit != row.unit {
+			commonUnit = ""
+		}
+	}
+
+	valueField := data.NewField("Value", nil, values)
+	if unit := grafanaUnitFromPRTG(commonUnit); unit != "" {
+		valueField.SetConfig(&data.FieldConfig{Unit: unit})
+	}
+
+	return data.NewFrame(frameName,
+		data.NewField("Time", nil, times),
+		data.NewField("Sensor ID", nil, sensorIds),
+		data.NewField("Group", nil, groups),
+		data.NewField("Device", nil, devices),
+		data.NewField("Sensor", nil, sensors),
+		data.NewField("Channel", nil, channels),
+		valueField,
+		data.NewField("Unit", nil, units),
+		data.NewField("Status", nil, statuses),
+	)
+}
+
+// sortLastValueSensors sorts sensor rows by device and sensor name
+func sortLastValueSensors(rows []map[string]interface{}) {
+	sortRows(rows, tableColumn{source: "sensor", kind: columnText}, false)
+	sortRows(rows, tableColumn{source: "device", kind: columnText}, false)
+}
+
+// rowTime returns an OLE date column of a table.json row as time, nil if it is empty
+func rowTime(row map[string]interface{}, column string, loc *time.Location) *time.Time {
+	raw, ok := rowFloat(row, column)
+	if !ok || raw <= 0 {
+		return nil
+	}
+	t := oleDateToTime(raw, loc)
+	return &t
+}
@@ -0,0 +1,34 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestLastChannelValuesErrors(t *testing.T) {
+	sensors, api := multiSensorAPI()
+	ds := newTestDatasource(t, api, nil)
+	qm := queryModel{QueryType: "lastvalue", ChannelIds: []string{"0"}}
+	row := func(s PrtgSensorListItemStruct) map[string]interface{} {
+		return map[string]interface{}{"objid": float64(s.ObjectId), "device": s.Device, "sensor": s.Sensor}
+	}
+	delete(api.channels, "1003")
+
+	rows, notices, err := ds.lastChannelValues(qm, []map[string]interface{}{row(sensors[0]), row(sensors[1]), row(sensors[2])}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].sensor.ObjectId != 1001 {
+		t.Errorf("rows = %v, want the channel of sensor 1001", rows)
+	}
+	frame := &data.Frame{Meta: &data.FrameMeta{Notices: notices}}
+	if !hasNotice(frame, "skipped: Traffic") || !hasNotice(frame, "Ping (1003): object 1003 not found") {
+		t.Errorf("notices = %v", notices)
+	}
+
+	if _, _, err := ds.lastChannelValues(qm, []map[string]interface{}{row(sensors[2])}, time.UTC); err == nil {
+		t.Error("expected an error if no sensor could be loaded")
+	}
+}
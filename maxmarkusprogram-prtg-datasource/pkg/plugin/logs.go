@@ -0,0 +1,194 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	// logColumns are the columns of PRTG's log (content=messages)
+	logColumns = "objid,datetime,parent,type,name,status,message"
+	// defaultLogLimit limits the log entries of a query without limit
+	defaultLogLimit = 1000
+)
+
+// logEntry is one message of the PRTG log
+type logEntry struct {
+	time time.Time
+	row  map[string]interface{}
+}
+
+/* =================================== LOGS QUERY ============================================== */
+
+// handleLogsQuery returns the PRTG log messages of the time range as logs frame. The log is
+// restricted to the selected sensors, devices or groups (with their children) and to message types.
+func (d *Datasource) handleLogsQuery(ctx context.Context, qm queryModel, timeRange backend.TimeRange, frameName string) backend.DataResponse {
+	_, span := d.tracer.StartSpan(ctx, "handleLogsQuery")
+	defer span.End()
+
+	loc, err := d.queryLocation(qm.Timezone)
+	if err != nil {
+		d.metrics.IncError("invalid_timezone")
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	limit := qm.Limit
+	if limit <= 0 {
+		limit = defaultLogLimit
+	}
+
+	// The most specific selection wins, without one the log of all objects is returned
+	objectIds := qm.sensorIds()
+	if len(objectIds) == 0 {
+		objectIds = qm.deviceIds()
+	}
+	if len(objectIds) == 0 {
+		objectIds = qm.groupIds()
+	}
+	if len(objectIds) == 0 {
+		objectIds = []string{""}
+	}
+
+	types := make(map[string]struct{})
+	for _, t := range qm.LogTypes {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			types[t] = struct{}{}
+		}
+	}
+
+	// Every object gets an equal share of the limit, so all requests together load at most limit rows
+	count := max(limit/int64(len(objectIds)), 1)
+	truncated := false
+
+	// PRTG expects the time filter in its own timezone
+	const format = "2006-01-02-15-04-05"
+	entries := make([]logEntry, 0)
+	for _, id := range objectIds {
+		params := map[string]string{
+			"columns":       logColumns,
+			"filter_dstart": timeRange.From.In(loc).Format(format),
+			"filter_dend":   timeRange.To.In(loc).Format(format),
+			"count":         strconv.FormatInt(count, 10),
+		}
+		if id != "" {
+			params["id"] = id
+		}
+
+		rows, err := d.api.GetTable("messages", params)
+		if err != nil {
+			d.logger.Error("Logs query failed", "objectId", id, "error", err)
+			recordError(span, err, "Logs query failed")
+			return backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("API request failed: %v", err))
+		}
+		truncated = truncated || int64(len(rows)) >= count
+		for _, row := range rows {
+			if _, ok := types[strings.ToLower(rowString(row, "status"))]; len(types) > 0 && !ok {
+				continue
+			}
+			t := rowTime(row, "datetime_raw", loc)
+			if t == nil || t.Before(timeRange.From) || t.After(timeRange.To) {
+				continue
+			}
+			entries = append(entries, logEntry{time: *t, row: row})
+		}
+	}
+
+	// Newest entries first, as in the PRTG log
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].time.After(entries[j].time) })
+	notices := make([]data.Notice, 0)
+	if int64(len(entries)) > limit {
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("%d log entries found, only the newest %d are shown", len(entries), limit),
+		})
+		entries = entries[:limit]
+	} else if truncated {
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text: fmt.Sprintf("The log is limited to %d entries per object (limit %d, %d objects), older entries may be missing",
+				count, limit, len(objectIds)),
+		})
+	}
+
+	frame, err := logFrame(frameName, entries)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusInternal, err.Error())
+	}
+	frame.AppendNotices(notices...)
+	return backend.DataResponse{Frames: []*data.Frame{frame}}
+}
+
+// logFrame builds a frame of the logs data plane format: timestamp, body, severity, id and labels
+func logFrame(frameName string, entries []logEntry) (*data.Frame, error) {
+	timestamps := make([]time.Time, len(entries))
+	bodies := make([]string, len(entries))
+	severities := make([]string, len(entries))
+	ids := make([]string, len(entries))
+	labels := make([]json.RawMessage, len(entries))
+
+	for i, entry := range entries {
+		timestamps[i] = entry.time
+		status := rowString(entry.row, "status")
+		bodies[i] = cleanMessageHTML(rowString(entry.row, "message"))
+		if bodies[i] == "" {
+			bodies[i] = status
+		}
+		severities[i] = logLevel(status)
+
+		objid := rowString(entry.row, "objid")
+		// PRTG has no id for log entries, time, object and type identify them
+		ids[i] = fmt.Sprintf("%d_%s_%s_%d", entry.time.UnixNano(), objid, rowString(entry.row, "status_raw"), i)
+
+		entryLabels := data.Labels{}
+		setLabel(entryLabels, "object", rowString(entry.row, "name"))
+		setLabel(entryLabels, "objid", objid)
+		setLabel(entryLabels, "type", rowString(entry.row, "type"))
+		setLabel(entryLabels, "parent", rowString(entry.row, "parent"))
+		setLabel(entryLabels, "status", status)
+		raw, err := json.Marshal(entryLabels)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling log labels: %w", err)
+		}
+		labels[i] = raw
+	}
+
+	frame := data.NewFrame(frameName,
+		data.NewField("timestamp", nil, timestamps),
+		data.NewField("body", nil, bodies),
+		data.NewField("severity", nil, severities),
+		data.NewField("id", nil, ids),
+		data.NewField("labels", nil, labels),
+	)
+	frame.Meta = &data.FrameMeta{
+		Type:                   data.FrameTypeLogLines,
+		TypeVersion:            data.FrameTypeVersion{0, 0},
+		PreferredVisualization: data.VisTypeLogs,
+		Custom: map[string]interface{}{
+			"queryType": "logs",
+			"entries":   len(entries),
+		},
+	}
+	return frame, nil
+}
+
+// logLevel derives the Grafana log level from the status of a log entry
+func logLevel(status string) string {
+	status = strings.ToLower(status)
+	switch {
+	case strings.Contains(status, "down"), strings.Contains(status, "error"):
+		return "error"
+	case strings.Contains(status, "warning"), strings.Contains(status, "unusual"):
+		return "warning"
+	case strings.Contains(status, "unknown"):
+		return "unknown"
+	default:
+		return "info"
+	}
+}
@@ -0,0 +1,58 @@
+package plugin
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestLogsQueryLimit(t *testing.T) {
+	end := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	// Every object has an entry per minute, PRTG returns the newest count entries
+	api := &fakeAPI{tables: func(content string, params map[string]string) []map[string]interface{} {
+		count, _ := strconv.Atoi(params["count"])
+		rows := make([]map[string]interface{}, 0, count)
+		for i := 0; i < count; i++ {
+			rows = append(rows, map[string]interface{}{
+				"objid":        params["id"],
+				"datetime_raw": oleDate(end.Add(-time.Duration(i+1) * time.Minute)),
+				"status":       "Up",
+				"message":      "OK",
+			})
+		}
+		return rows
+	}}
+	ds := newTestDatasource(t, api, nil)
+	timeRange := backend.TimeRange{From: end.Add(-24 * time.Hour), To: end}
+	qm := queryModel{QueryType: "logs", SensorIds: []string{"1001", "1002", "1003", "1004"}, Limit: 100}
+
+	res := ds.handleLogsQuery(context.Background(), qm, timeRange, "A")
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	for _, params := range api.tableRequests {
+		if params["count"] != "25" {
+			t.Errorf("object %s requested count %s, want 25", params["id"], params["count"])
+		}
+	}
+	frame := res.Frames[0]
+	if frame.Rows() != 100 {
+		t.Errorf("got %d entries, want 100", frame.Rows())
+	}
+	if !hasNotice(frame, "limited to 25 entries per object") {
+		t.Errorf("notices = %v, want the per object limit", frame.Meta.Notices)
+	}
+
+	// More objects than the limit: one entry each, trimmed to the limit
+	qm.Limit = 2
+	res = ds.handleLogsQuery(context.Background(), qm, timeRange, "A")
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if frame := res.Frames[0]; frame.Rows() != 2 || !hasNotice(frame, "4 log entries found, only the newest 2") {
+		t.Errorf("got %d entries, notices %v", frame.Rows(), frame.Meta.Notices)
+	}
+}
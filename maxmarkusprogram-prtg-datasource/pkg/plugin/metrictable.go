@@ -0,0 +1,228 @@
+package plugin
+
+import (
+	"time"
+)
+
+// gapThresholdFactor is the multiple of the expected sample spacing after which two
+// consecutive samples are considered to be separated by an outage
+const gapThresholdFactor = 2
+
+// Null value modes of a metrics query
+const (
+	NullValueModeNull     = "null"     // keep missing values as null (default)
+	NullValueModePrevious = "previous" // repeat the last known value
+	NullValueModeZero     = "zero"     // replace missing values with 0
+)
+
+// Timestamp alignments of averaged rows. PRTG stamps every row with the end of its interval.
+const (
+	TimestampAlignmentStart    = "start"
+	TimestampAlignmentMidpoint = "midpoint"
+	TimestampAlignmentEnd      = "end" // default
+)
+
+/* =================================== METRIC TABLE ============================================ */
+
+// metricTable holds the parsed historic data of one sensor on a common time axis.
+// Every column has one (possibly nil) value per timestamp.
+type metricTable struct {
+	Times   []time.Time
+	Columns []*metricColumn
+
+	// Bounds of the averaging interval per row, only set by alignTimestamps.
+	// Rows inserted for gaps have no bounds.
+	IntervalStarts []*time.Time
+	IntervalEnds   []*time.Time
+}
+
+type metricColumn struct {
+	Selection channelSelection
+	Values    []*float64
+
+	// Unit set by transforms (Grafana unit id), empty keeps the unit of the PRTG channel
+	Unit string
+	// Transformed columns no longer match the limits and lookups of the PRTG channel
+	Transformed bool
+}
+
+// unit returns the Grafana unit of the column values
+func (c *metricColumn) unit() string {
+	if c.Unit != "" {
+		return c.Unit
+	}
+	if c.Selection.Info == nil {
+		return ""
+	}
+	return grafanaUnitFromPRTG(c.Selection.Info.Unit)
+}
+
+// parseMetricTable converts the historic data rows into a metric table. Rows without a
+// value for any selected channel are skipped, missing values of single channels stay nil.
+// The returned map counts values which were present but could not be parsed per channel.
+// Timestamps are interpreted in loc, nil uses the datasource timezone; formatted values are
+// parsed with the decimal separator of the PRTG server.
+func parseMetricTable(historicalData *PrtgHistoricalDataResponse, selections []channelSelection, loc *time.Location, decimal string) (*metricTable, map[string]int) {
+	table := &metricTable{
+		Times:   make([]time.Time, 0),
+		Columns: make([]*metricColumn, 0, len(selections)),
+	}
+	for _, sel := range selections {
+		table.Columns = append(table.Columns, &metricColumn{
+			Selection: sel,
+			Values:    make([]*float64, 0),
+		})
+	}
+	dropped := make(map[string]int)
+
+	if historicalData == nil {
+		return table, dropped
+	}
+
+	row := make([]*float64, len(selections))
+	for _, item := range historicalData.HistData {
+		parsedTime, err := parsePRTGTimestamp(item.DatetimeRAW, item.Datetime, loc)
+		if err != nil {
+			continue
+		}
+
+		hasData := false
+		for i, sel := range selections {
+			row[i] = nil
+			val, exists := item.Value[sel.Column]
+			if !exists {
+				continue
+			}
+			floatVal, ok := parseHistoricValue(item.Value, sel.Column, decimal)
+			if !ok {
+				if !isBlankValue(val) {
+					dropped[sel.Name]++
+				}
+				continue
+			}
+			row[i] = &floatVal
+			hasData = true
+		}
+
+		if !hasData {
+			continue
+		}
+		table.Times = append(table.Times, parsedTime)
+		for i, column := range table.Columns {
+			column.Values = append(column.Values, row[i])
+		}
+	}
+
+	return table, dropped
+}
+
+// hasIntervalBounds reports whether alignTimestamps recorded the interval bounds of all rows
+func (t *metricTable) hasIntervalBounds() bool {
+	return t.IntervalStarts != nil && len(t.IntervalStarts) == len(t.Times)
+}
+
+// alignTimestamps moves the timestamp of every row to the start, midpoint or end of its
+// averaging interval and records the interval bounds. Raw data (no averaging) is left as is.
+func (t *metricTable) alignTimestamps(mode string, interval time.Duration) {
+	t.IntervalStarts = make([]*time.Time, len(t.Times))
+	t.IntervalEnds = make([]*time.Time, len(t.Times))
+
+	for i, end := range t.Times {
+		start := intervalStart(end, interval)
+		t.IntervalStarts[i] = &start
+		t.IntervalEnds[i] = &end
+
+		switch mode {
+		case TimestampAlignmentStart:
+			t.Times[i] = start
+		case TimestampAlignmentMidpoint:
+			t.Times[i] = start.Add(end.Sub(start) / 2)
+		}
+	}
+}
+
+// intervalStart returns the start of the averaging interval ending at end. Whole days are
+// subtracted on the calendar so daily averages stay aligned to midnight across DST changes.
+func intervalStart(end time.Time, interval time.Duration) time.Time {
+	const day = 24 * time.Hour
+	if interval >= day && interval%day == 0 {
+		return end.AddDate(0, 0, -int(interval/day))
+	}
+	return end.Add(-interval)
+}
+
+// fillNulls replaces missing values according to the null value mode of the query.
+func (t *metricTable) fillNulls(mode string) {
+	switch mode {
+	case NullValueModePrevious:
+		for _, column := range t.Columns {
+			var previous *float64
+			for i, value := range column.Values {
+				if value == nil {
+					column.Values[i] = previous
+				} else {
+					previous = value
+				}
+			}
+		}
+	case NullValueModeZero:
+		for _, column := range t.Columns {
+			for i, value := range column.Values {
+				if value == nil {
+					zero := 0.0
+					column.Values[i] = &zero
+				}
+			}
+		}
+	}
+}
+
+// insertGaps adds a null row after every sample that is followed by a pause longer than
+// the expected spacing, so time series panels do not connect the line across outages.
+// Returns the number of inserted gaps.
+func (t *metricTable) insertGaps(expected time.Duration) int {
+	if expected <= 0 || len(t.Times) < 2 {
+		return 0
+	}
+
+	threshold := time.Duration(gapThresholdFactor) * expected
+	gaps := 0
+	times := make([]time.Time, 0, len(t.Times))
+	values := make([][]*float64, len(t.Columns))
+	hasBounds := t.hasIntervalBounds()
+	starts := make([]*time.Time, 0, len(t.Times))
+	ends := make([]*time.Time, 0, len(t.Times))
+
+	for i, ts := range t.Times {
+		if i > 0 && ts.Sub(t.Times[i-1]) > threshold {
+			times = append(times, t.Times[i-1].Add(expected))
+			for c := range t.Columns {
+				values[c] = append(values[c], nil)
+			}
+			starts = append(starts, nil)
+			ends = append(ends, nil)
+			gaps++
+		}
+		times = append(times, ts)
+		for c, column := range t.Columns {
+			values[c] = append(values[c], column.Values[i])
+		}
+		if hasBounds {
+			starts = append(starts, t.IntervalStarts[i])
+			ends = append(ends, t.IntervalEnds[i])
+		}
+	}
+
+	if gaps == 0 {
+		return 0
+	}
+	t.Times = times
+	if hasBounds {
+		t.IntervalStarts = starts
+		t.IntervalEnds = ends
+	}
+	for c, column := range t.Columns {
+		column.Values = values[c]
+	}
+	return gaps
+}
@@ -0,0 +1,228 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+/* =================================== OBJECT ID LISTS ========================================= */
+
+// splitIds returns the ids of a list field and of an interpolated id field. Multi-value
+// variables expand to "1,2,3" or "{1,2,3}". Duplicates are removed, the order is kept.
+func splitIds(list []string, single string) []string {
+	ids := make([]string, 0, len(list)+1)
+	seen := make(map[string]struct{})
+	for _, value := range append(append([]string{}, list...), single) {
+		value = strings.Trim(strings.TrimSpace(value), "{}")
+		for _, id := range strings.Split(value, ",") {
+			id = strings.TrimSpace(id)
+			if id == "" {
+				continue
+			}
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (qm queryModel) sensorIds() []string { return splitIds(qm.SensorIds, qm.SensorId) }
+func (qm queryModel) deviceIds() []string { return splitIds(qm.DeviceIds, qm.DeviceId) }
+func (qm queryModel) groupIds() []string  { return splitIds(qm.GroupIds, qm.GroupId) }
+
+// matchesObject compares by id if the query has one, otherwise by name
+func matchesObject(objid int64, name, wantId, wantName string) bool {
+	if wantId != "" {
+		return strconv.FormatInt(objid, 10) == wantId
+	}
+	return name == wantName
+}
+
+/* =================================== MULTI SENSOR METRICS ==================================== */
+
+// multiSensorIds returns the sensors a metrics query expands to: several sensor ids, or all
+// sensors of the selected devices or groups if no sensor is selected. multi is false for
+// a query of a single sensor.
+func (d *Datasource) multiSensorIds(qm queryModel) (sensors []PrtgSensorListItemStruct, multi bool, err error) {
+	if qm.Expression != "" {
+		return nil, false, nil
+	}
+	adhoc, err := newAdhocMatchers(qm.AdhocFilters)
+	if err != nil {
+		return nil, false, err
+	}
+
+	sensorIds := qm.sensorIds()
+	if len(sensorIds) > 1 {
+		sensors = make([]PrtgSensorListItemStruct, 0, len(sensorIds))
+		for _, id := range sensorIds {
+			sensor, err := d.api.GetSensorDetails(id)
+			if err != nil {
+				return nil, true, fmt.Errorf("sensor %s: %w", id, err)
+			}
+			sensors = append(sensors, *sensor)
+		}
+		return filterSensors(adhoc, sensors), true, nil
+	}
+	if len(sensorIds) == 1 {
+		return nil, false, nil
+	}
+
+	parentIds := qm.deviceIds()
+	if len(parentIds) == 0 {
+		parentIds = qm.groupIds()
+	}
+	if len(parentIds) == 0 {
+		return nil, false, nil
+	}
+
+	seen := make(map[int64]struct{})
+	for _, parentId := range parentIds {
+		params := map[string]string{
+			"columns": sensorTableColumns,
+			"id":      parentId,
+		}
+		remaining, _ := applyAdhocParams(adhoc, sensorTableColumns, params)
+		rows, err := d.api.GetTable("sensors", params)
+		if err != nil {
+			return nil, true, fmt.Errorf("sensors of object %s: %w", parentId, err)
+		}
+		for _, row := range rows {
+			if !matchesAdhoc(remaining, row) {
+				continue
+			}
+			sensor := sensorFromRow(row)
+			if _, ok := seen[sensor.ObjectId]; ok || sensor.ObjectId == 0 {
+				continue
+			}
+			seen[sensor.ObjectId] = struct{}{}
+			sensors = append(sensors, sensor)
+		}
+	}
+	sort.SliceStable(sensors, func(i, j int) bool {
+		if sensors[i].Device != sensors[j].Device {
+			return sensors[i].Device < sensors[j].Device
+		}
+		return sensors[i].Sensor < sensors[j].Sensor
+	})
+	return sensors, true, nil
+}
+
+// sensorMatchesAdhoc reports whether the sensor of a single sensor query passes the ad-hoc filters
+func (d *Datasource) sensorMatchesAdhoc(sensorId string, filters []adhocFilter) (bool, error) {
+	adhoc, err := newAdhocMatchers(filters)
+	if err != nil || len(adhoc) == 0 {
+		return err == nil, err
+	}
+	sensor, err := d.api.GetSensorDetails(sensorId)
+	if err != nil {
+		return false, fmt.Errorf("sensor %s: %w", sensorId, err)
+	}
+	return matchesAdhoc(adhoc, sensorRow(*sensor)), nil
+}
+
+// sensorTableColumns are the columns of sensor lists loaded with GetTable
+const sensorTableColumns = "objid,probe,group,device,sensor,status,tags,type,priority"
+
+// sensorFromRow converts a row of a sensor table.json request
+func sensorFromRow(row map[string]interface{}) PrtgSensorListItemStruct {
+	objid, _ := strconv.ParseInt(rowString(row, "objid"), 10, 64)
+	return PrtgSensorListItemStruct{
+		ObjectId: objid,
+		Probe:    rowString(row, "probe"),
+		Group:    rowString(row, "group"),
+		Device:   rowString(row, "device"),
+		Sensor:   rowString(row, "sensor"),
+		Status:   rowString(row, "status"),
+		Tags:     rowString(row, "tags"),
+		Type:     rowString(row, "type"),
+	}
+}
+
+/* =================================== MULTI OBJECT PROPERTIES ================================= */
+
+// propertyObjectIds returns the ids of the objects a property query reads
+func (qm queryModel) propertyObjectIds(property string) []string {
+	switch property {
+	case "group":
+		return qm.groupIds()
+	case "device":
+		return qm.deviceIds()
+	case "sensor":
+		return qm.sensorIds()
+	}
+	return nil
+}
+
+// objectQuery derives the property query of one object id. The names are taken from PRTG,
+// so the query also works if the id comes from a variable and the stored names are outdated.
+func (d *Datasource) objectQuery(qm queryModel, property, id string) (queryModel, error) {
+	objectQuery := qm
+	objectQuery.GroupIds, objectQuery.DeviceIds, objectQuery.SensorIds = nil, nil, nil
+
+	switch property {
+	case "sensor":
+		sensor, err := d.api.GetSensorDetails(id)
+		if err != nil {
+			return qm, fmt.Errorf("sensor %s: %w", id, err)
+		}
+		objectQuery.SensorId = id
+		objectQuery.Group, objectQuery.Device, objectQuery.Sensor = sensor.Group, sensor.Device, sensor.Sensor
+	case "device":
+		row, err := d.objectRow("devices", "objid,device,group", id)
+		if err != nil {
+			return qm, err
+		}
+		objectQuery.DeviceId = id
+		objectQuery.Group, objectQuery.Device = rowString(row, "group"), rowString(row, "device")
+	case "group":
+		row, err := d.objectRow("groups", "objid,group", id)
+		if err != nil {
+			return qm, err
+		}
+		objectQuery.GroupId = id
+		objectQuery.Group = rowString(row, "group")
+	}
+	return objectQuery, nil
+}
+
+func (d *Datasource) objectRow(content, columns, id string) (map[string]interface{}, error) {
+	rows, err := d.api.GetTable(content, map[string]string{
+		"columns":      columns,
+		"filter_objid": id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("object %s not found", id)
+	}
+	return rows[0], nil
+}
+
+// handleMultiObjectPropertyQuery runs a property query per object id and returns one frame per object
+func (d *Datasource) handleMultiObjectPropertyQuery(ctx context.Context, qm queryModel, ids []string, property, filterProperty, baseFrameName string) backend.DataResponse {
+	response := backend.DataResponse{Frames: make([]*data.Frame, 0, len(ids))}
+	for _, id := range ids {
+		objectQuery, err := d.objectQuery(qm, property, id)
+		if err != nil {
+			d.metrics.IncError("object_not_found")
+			return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+		}
+		res := d.handlePropertyQuery(ctx, objectQuery, property, filterProperty, fmt.Sprintf("%s_%s", baseFrameName, id))
+		if res.Error != nil {
+			return res
+		}
+		response.Frames = append(response.Frames, res.Frames...)
+	}
+	return response
+}
@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -10,15 +11,89 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 )
 
-var defaultTimezone = "Avrupa/Istanbul" // Default PRTG timezone
+// fallbackTimezone is used where no datasource location is available, it matches the settings default
+const fallbackTimezone = "Europe/Berlin"
 
-// SetDefaultTimezone sets the default timezone for parsing dates
-// Call this during plugin initialization with the timezone from settings
-func SetDefaultTimezone(timezone string) {
-	if timezone != "" {
-		defaultTimezone = timezone
-		backend.Logger.Info("Setting default timezone for date parsing", "timezone", timezone)
+// fallbackLocation returns the location of fallbackTimezone, UTC if it cannot be loaded
+func fallbackLocation() *time.Location {
+	if loc, err := time.LoadLocation(fallbackTimezone); err == nil {
+		return loc
 	}
+	return time.UTC
+}
+
+// loadTimezone returns the location of the configured timezone, UTC if it is invalid
+func loadTimezone(timezone string) *time.Location {
+	if timezone == "" {
+		return fallbackLocation()
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		backend.Logger.Warn("Failed to load timezone, using UTC", "timezone", timezone, "error", err)
+		return time.UTC
+	}
+	return loc
+}
+
+// serverTimeRetryDelay is the time after which a failed timezone detection is retried
+const serverTimeRetryDelay = time.Minute
+
+// location returns the location PRTG timestamps of this datasource are interpreted in. With
+// autoTimezone the PRTG server clock is checked on first use, see detectServerTime. If PRTG
+// cannot be reached, the detection is retried after serverTimeRetryDelay.
+func (d *Datasource) location() *time.Location {
+	if d.settings != nil && d.settings.AutoTimezone {
+		d.detectLocation()
+	}
+
+	d.locationMu.RLock()
+	defer d.locationMu.RUnlock()
+	if d.loc == nil {
+		return fallbackLocation()
+	}
+	return d.loc
+}
+
+// detectLocation applies the PRTG server clock, unless it was detected before or the last
+// attempt failed less than serverTimeRetryDelay ago
+func (d *Datasource) detectLocation() {
+	d.serverTimeMu.Lock()
+	defer d.serverTimeMu.Unlock()
+	if d.serverTimeDetected || time.Now().Before(d.serverTimeRetry) {
+		return
+	}
+
+	status, err := d.api.GetStatusList()
+	if err != nil {
+		d.logger.Warn("Failed to fetch PRTG status for timezone detection", "error", err, "retryIn", serverTimeRetryDelay)
+		d.serverTimeRetry = time.Now().Add(serverTimeRetryDelay)
+		return
+	}
+	d.serverTimeDetected = true
+	d.applyServerTime(status, d.settings)
+}
+
+// setLocation replaces the location of this datasource, e.g. with the detected server offset
+func (d *Datasource) setLocation(loc *time.Location) {
+	if loc == nil {
+		return
+	}
+	d.locationMu.Lock()
+	d.loc = loc
+	d.locationMu.Unlock()
+	d.logger.Info("Setting location for date parsing", "location", loc.String())
+}
+
+// queryLocation returns the location of a query timezone, the datasource location if none is set
+func (d *Datasource) queryLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return d.location(), nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone '%s': %w", timezone, err)
+	}
+	return loc, nil
 }
 
 // ParseTimeInit provides settings to the parse_time functions
@@ -27,51 +102,64 @@ func ParseTimeInit(s *models.PluginSettings) {
 	// Implementation reserved for future use
 }
 
-func parsePRTGDateTime(datetime string) (time.Time, string, error) {
-	// Log which timezone is being used for parsing
-	backend.Logger.Info("Parsing datetime", "input", datetime, "timezone", defaultTimezone)
+// oleEpoch is day zero of OLE automation dates as used by PRTG's datetime_raw columns
+var oleEpoch = struct{ year, month, day int }{1899, 12, 30}
+
+// oleDateToTime converts an OLE automation date (days since 30.12.1899, fraction = time of day)
+// to a time. The value is PRTG server wall clock time and therefore interpreted in loc.
+func oleDateToTime(raw float64, loc *time.Location) time.Time {
+	const msPerDay = 24 * 60 * 60 * 1000
+	totalMs := int64(math.Round(raw * msPerDay))
+	days := totalMs / msPerDay
+	msOfDay := totalMs % msPerDay
+
+	return time.Date(oleEpoch.year, time.Month(oleEpoch.month), oleEpoch.day+int(days),
+		0, 0, 0, int(msOfDay)*int(time.Millisecond), loc)
+}
+
+// parsePRTGTimestamp prefers the unambiguous raw timestamp of a PRTG row and only parses
+// the localized datetime string if no raw value is available. A nil loc uses the fallback timezone.
+func parsePRTGTimestamp(raw float64, datetime string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = fallbackLocation()
+	}
+	if raw > 0 {
+		return oleDateToTime(raw, loc), nil
+	}
+	parsed, _, err := parsePRTGDateTimeIn(datetime, loc)
+	return parsed, err
+}
+
+// parsePRTGDateTimeIn parses a localized PRTG datetime string in the given location
+func parsePRTGDateTimeIn(datetime string, sourceLoc *time.Location) (time.Time, string, error) {
 	// Remove any whitespace
 	datetime = strings.TrimSpace(datetime)
 
 	// If datetime contains a range (e.g., "06.03.2025 15:11:00 - 15:12:00")
 	if strings.Contains(datetime, " - ") {
 		parts := strings.Split(datetime, " - ")
-		datePart := strings.TrimSpace(strings.Split(parts[0], " ")[0])
-		startTime := strings.TrimSpace(strings.Split(parts[0], " ")[1])
-		endTime := strings.TrimSpace(parts[1])
-
-		// Construct the full datetime string with end time
-		datetime = datePart + " " + endTime
-
-		// Parse start time to compare
-		startTimeStr := datePart + " " + startTime
-		loc, err := time.LoadLocation(defaultTimezone)
-		if err != nil {
-			loc = time.UTC
-			backend.Logger.Warn("Failed to load default timezone, using UTC",
-				"timezone", defaultTimezone,
-				"error", err)
-		}
-
-		startDateTime, err := time.ParseInLocation("02.01.2006 15:04:05", startTimeStr, loc)
-		if err == nil {
-			endDateTime, err := time.ParseInLocation("02.01.2006 15:04:05", datetime, loc)
-			if err == nil && endDateTime.Before(startDateTime) {
-				// If end time is before start time, add one day
-				datetime = endDateTime.AddDate(0, 0, 1).Format("02.01.2006 15:04:05")
+		startParts := strings.Split(strings.TrimSpace(parts[0]), " ")
+		if len(startParts) >= 2 {
+			datePart := startParts[0]
+			startTime := startParts[1]
+			endTime := strings.TrimSpace(parts[1])
+
+			// Construct the full datetime string with end time
+			datetime = datePart + " " + endTime
+
+			// Parse start time to compare
+			startTimeStr := datePart + " " + startTime
+			startDateTime, err := time.ParseInLocation("02.01.2006 15:04:05", startTimeStr, sourceLoc)
+			if err == nil {
+				endDateTime, err := time.ParseInLocation("02.01.2006 15:04:05", datetime, sourceLoc)
+				if err == nil && endDateTime.Before(startDateTime) {
+					// If end time is before start time, add one day
+					datetime = endDateTime.AddDate(0, 0, 1).Format("02.01.2006 15:04:05")
+				}
 			}
 		}
 	}
 
-	// Use the configured timezone
-	sourceLoc, err := time.LoadLocation(defaultTimezone)
-	if err != nil {
-		sourceLoc = time.UTC
-		backend.Logger.Warn("Failed to load default timezone, using UTC",
-			"timezone", defaultTimezone,
-			"error", err)
-	}
-
 	// User's local timezone for display
 	destLoc := time.Local
 
@@ -112,11 +200,5 @@ func parsePRTGDateTime(datetime string) (time.Time, string, error) {
 		lastErr = err
 	}
 
-	// Log the parsing failure
-	backend.Logger.Error("Failed to parse datetime",
-		"input", datetime,
-		"error", lastErr,
-	)
-
 	return time.Time{}, "", fmt.Errorf("failed to parse datetime '%s': %v", datetime, lastErr)
 }
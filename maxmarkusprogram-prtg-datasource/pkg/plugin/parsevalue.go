@@ -0,0 +1,148 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// rawColumnSuffixes are the suffixes PRTG uses for the raw (unformatted) variant of a column
+var rawColumnSuffixes = []string{" (RAW)", " (raw)", "(RAW)", "_raw", "_RAW"}
+
+// leadingNumber matches the numeric part of a formatted value like "1.234,5 MByte", "< 1 %" or "1e-05"
+var leadingNumber = regexp.MustCompile(`^[<>~]?\s*([-+]?[0-9][0-9.,' ]*(?:[eE][-+]?[0-9]+)?)`)
+
+// Decimal separators of formatted PRTG values, they depend on the language of the PRTG server.
+// With DecimalAuto the separator is guessed from the value, see normalizeSeparators.
+const (
+	DecimalAuto  = ""
+	DecimalPoint = "."
+	DecimalComma = ","
+)
+
+// parseHistoricValue returns the numeric value of a historic data column. The raw column is
+// preferred; the formatted column is used if PRTG sends no raw value, a formatted string is
+// parsed with the decimal separator of the PRTG server.
+func parseHistoricValue(values map[string]interface{}, column, decimal string) (float64, bool) {
+	val, exists := values[column]
+	if !exists {
+		return 0, false
+	}
+
+	for _, suffix := range rawColumnSuffixes {
+		switch raw := values[column+suffix].(type) {
+		case float64:
+			return raw, true
+		case string:
+			// Raw values are not localized
+			if num, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil {
+				return num, true
+			}
+		}
+	}
+
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case string:
+		return parseLocaleFloat(v, decimal)
+	}
+	return 0, false
+}
+
+// isBlankValue reports whether PRTG sent no value at all for a column (null or "")
+func isBlankValue(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+	str, ok := val.(string)
+	return ok && strings.TrimSpace(str) == ""
+}
+
+// parseLocaleFloat parses a formatted PRTG value, e.g. "1.234,5", "1,234.5", "12 %" or
+// "3,2 Mbit/s". Unit suffixes are ignored. Plain numbers and numbers with a unit follow the
+// same separator rule, so "1.234" and "1.234 MByte" are always the same number.
+func parseLocaleFloat(value, decimal string) (float64, bool) {
+	value = strings.NewReplacer("\u00a0", " ", "\u202f", " ").Replace(strings.TrimSpace(value))
+	match := leadingNumber.FindStringSubmatch(value)
+	if match == nil {
+		return 0, false
+	}
+	number := strings.NewReplacer(" ", "", "'", "").Replace(match[1])
+	number = strings.TrimRight(number, ".,")
+	if number == "" {
+		return 0, false
+	}
+
+	num, err := strconv.ParseFloat(normalizeSeparators(number, decimal), 64)
+	if err != nil {
+		return 0, false
+	}
+	return num, true
+}
+
+// normalizeSeparators converts a number with thousands and decimal separators to Go syntax.
+// A configured decimal separator makes the other one the thousands separator. Without one,
+// the last of two different separators is the decimal separator and a single kind is
+// resolved by normalizeSingleSeparator.
+func normalizeSeparators(number, decimal string) string {
+	switch decimal {
+	case DecimalComma:
+		return strings.Replace(strings.ReplaceAll(number, ".", ""), ",", ".", 1)
+	case DecimalPoint:
+		return strings.ReplaceAll(number, ",", "")
+	}
+
+	lastDot := strings.LastIndex(number, ".")
+	lastComma := strings.LastIndex(number, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastComma > lastDot {
+			return strings.Replace(strings.ReplaceAll(number, ".", ""), ",", ".", 1)
+		}
+		return strings.ReplaceAll(number, ",", "")
+	case lastComma >= 0:
+		return normalizeSingleSeparator(number, ",")
+	case lastDot >= 0:
+		return normalizeSingleSeparator(number, ".")
+	}
+	return number
+}
+
+// normalizeSingleSeparator decides whether a separator is a thousands or a decimal separator.
+// Repeated separators and groups of exactly three digits after a non-zero integer part
+// are thousands separators ("1.234 MByte", "1,234,567"), anything else is a decimal separator ("3,2").
+func normalizeSingleSeparator(number, sep string) string {
+	parts := strings.Split(number, sep)
+	integerPart := strings.TrimLeft(parts[0], "+-")
+
+	if len(parts) > 2 || (len(parts[1]) == 3 && integerPart != "0" && integerPart != "") {
+		return strings.ReplaceAll(number, sep, "")
+	}
+	return strings.Replace(number, sep, ".", 1)
+}
+
+// droppedValuesNotice builds a frame notice listing the number of unparsable values per channel
+func droppedValuesNotice(dropped map[string]int) *data.Notice {
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	channels := make([]string, 0, len(dropped))
+	total := 0
+	for channel, count := range dropped {
+		channels = append(channels, fmt.Sprintf("%s: %d", channel, count))
+		total += count
+	}
+	sort.Strings(channels)
+
+	return &data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text: fmt.Sprintf("%d values could not be parsed as numbers and were dropped (%s)",
+			total, strings.Join(channels, ", ")),
+	}
+}
@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestParseLocaleFloat(t *testing.T) {
+	tests := []struct {
+		value   string
+		decimal string
+		want    float64
+		ok      bool
+	}{
+		{"1.234", DecimalAuto, 1234, true},
+		{"1.234 MByte", DecimalAuto, 1234, true},
+		{"3,2 Mbit/s", DecimalAuto, 3.2, true},
+		{"1.234,5", DecimalAuto, 1234.5, true},
+		{"1,234.5", DecimalAuto, 1234.5, true},
+		{"0.125", DecimalAuto, 0.125, true},
+		{"1e-05", DecimalAuto, 0.00001, true},
+		{"< 1 %", DecimalAuto, 1, true},
+		{"1.234", DecimalPoint, 1.234, true},
+		{"1.234 MByte", DecimalPoint, 1.234, true},
+		{"2.500 s", DecimalPoint, 2.5, true},
+		{"1,234.5", DecimalPoint, 1234.5, true},
+		{"1.234", DecimalComma, 1234, true},
+		{"1.234,5 MByte", DecimalComma, 1234.5, true},
+		{"3,2", DecimalComma, 3.2, true},
+		{"", DecimalAuto, 0, false},
+		{"n/a", DecimalAuto, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseLocaleFloat(tt.value, tt.decimal)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseLocaleFloat(%q, %q) = %v, %v; want %v, %v", tt.value, tt.decimal, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseHistoricValuePrefersRaw(t *testing.T) {
+	values := map[string]interface{}{
+		"Traffic":       "1.234 kbit/s",
+		"Traffic (RAW)": 1234567.0,
+		"Load":          "12,5 %",
+		"Load (RAW)":    "",
+		"Count":         42.0,
+	}
+	tests := []struct {
+		column string
+		want   float64
+	}{
+		{"Traffic", 1234567},
+		{"Load", 12.5},
+		{"Count", 42},
+	}
+	for _, tt := range tests {
+		got, ok := parseHistoricValue(values, tt.column, DecimalComma)
+		if !ok || got != tt.want {
+			t.Errorf("parseHistoricValue(%q) = %v, %v; want %v", tt.column, got, ok, tt.want)
+		}
+	}
+	if _, ok := parseHistoricValue(values, "Missing", DecimalAuto); ok {
+		t.Error("parseHistoricValue of a missing column returned a value")
+	}
+}
+
+func TestOptionalFloatUnmarshal(t *testing.T) {
+	tests := []struct {
+		json    string
+		decimal string
+		want    float64
+		valid   bool
+	}{
+		{`80`, DecimalComma, 80, true},
+		{`"80"`, DecimalPoint, 80, true},
+		{`"1.234,5"`, DecimalComma, 1234.5, true},
+		{`"0,5"`, DecimalComma, 0.5, true},
+		{`"1.500"`, DecimalPoint, 1.5, true},
+		{`"1.500"`, DecimalComma, 1500, true},
+		{`"1,500"`, DecimalPoint, 1500, true},
+		{`"1,500"`, DecimalComma, 1.5, true},
+		{`""`, DecimalPoint, 0, false},
+		{`null`, DecimalPoint, 0, false},
+	}
+	for _, tt := range tests {
+		var o OptionalFloat
+		if err := json.Unmarshal([]byte(tt.json), &o); err != nil {
+			t.Fatalf("unmarshal %s: %v", tt.json, err)
+		}
+		if got, ok := o.Float(tt.decimal); ok != tt.valid || got != tt.want {
+			t.Errorf("unmarshal %s with separator %q = %v, %v; want %v, %v", tt.json, tt.decimal, got, ok, tt.want, tt.valid)
+		}
+
+		// The channel cache stores the raw value, the separator is applied after loading it
+		raw, err := json.Marshal(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cached OptionalFloat
+		if err := json.Unmarshal(raw, &cached); err != nil || cached != o {
+			t.Errorf("cache round trip of %s = %+v, %v; want %+v", tt.json, cached, err, o)
+		}
+	}
+}
+
+func TestThresholdsFromLocalizedLimits(t *testing.T) {
+	var channel PrtgChannelListItemStruct
+	if err := json.Unmarshal([]byte(`{"limitmaxwarning":"1.500","limitmaxerror":"2.500"}`), &channel); err != nil {
+		t.Fatal(err)
+	}
+	for decimal, want := range map[string][]float64{DecimalPoint: {1.5, 2.5}, DecimalComma: {1500, 2500}} {
+		config := &data.FieldConfig{}
+		applyChannelFieldConfig(config, &channel, decimal)
+		if config.Thresholds == nil || len(config.Thresholds.Steps) != 3 {
+			t.Fatalf("separator %q: thresholds = %+v", decimal, config.Thresholds)
+		}
+		for i, step := range config.Thresholds.Steps[1:] {
+			if float64(step.Value) != want[i] {
+				t.Errorf("separator %q: step %d = %v, want %v", decimal, i+1, step.Value, want[i])
+			}
+		}
+	}
+}
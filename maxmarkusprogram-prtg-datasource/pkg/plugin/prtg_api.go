@@ -3,11 +3,14 @@ package plugin
 import (
 	"crypto/tls"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
@@ -29,6 +32,41 @@ func (a *Api) ClearCache() {
 	defer a.cacheMu.Unlock()
 	a.cache = make(map[string]cacheItem)
 }
+
+// getCached liefert einen noch gültigen Cache-Eintrag.
+func (a *Api) getCached(key string) ([]byte, bool) {
+	a.cacheMu.RLock()
+	defer a.cacheMu.RUnlock()
+	if cached, exists := a.cache[key]; exists && time.Now().Before(cached.expiry) {
+		return cached.data, true
+	}
+	return nil, false
+}
+
+// setCached speichert eine Antwort für die konfigurierte Cache-Dauer.
+func (a *Api) setCached(key string, data []byte) {
+	a.setCachedFor(key, data, a.cacheTime)
+}
+
+// setCachedFor speichert eine Antwort für die angegebene Dauer.
+func (a *Api) setCachedFor(key string, data []byte, ttl time.Duration) {
+	a.cacheMu.Lock()
+	defer a.cacheMu.Unlock()
+	a.cache[key] = cacheItem{
+		data:   data,
+		expiry: time.Now().Add(ttl),
+	}
+}
+
+// liveCacheTime begrenzt die Cache-Dauer aktueller Werte (Last Value, Alarme), damit sie nicht
+// bis zur konfigurierten Cache-Dauer veralten.
+const liveCacheTime = 5 * time.Second
+
+// liveTTL liefert die Cache-Dauer für aktuelle Werte, höchstens die konfigurierte Cache-Dauer.
+func (a *Api) liveTTL() time.Duration {
+	return min(a.cacheTime, liveCacheTime)
+}
+
 // buildApiUrl erstellt eine standardisierte PRTG-API-URL mit übergebenen Parametern.
 func (a *Api) buildApiUrl(method string, params map[string]string) (string, error) {
 	baseUrl := fmt.Sprintf("%s/api/%s", a.baseURL, method)
@@ -188,7 +226,7 @@ func (a *Api) GetSensors(device string) (*PrtgSensorsListResponse, error) {
 
 	params := map[string]string{
 		"content":       "sensors",
-		"columns":       "active,channel,datetime,device,group,message,objid,priority,sensor,status,tags",
+		"columns":       "active,channel,datetime,device,group,message,objid,priority,probe,sensor,status,tags,type",
 		"count":         "50000",
 		"filter_device": device,
 	}
@@ -206,106 +244,285 @@ func (a *Api) GetSensors(device string) (*PrtgSensorsListResponse, error) {
 	return &response, nil
 }
 
-/* ====================================== CHANNEL HANDLER ======================================= */
-func (a *Api) GetChannels(objid string) (*PrtgChannelValueStruct, error) {
+// FindSensors liefert alle Sensoren, bei gesetztem tag nur die Sensoren mit diesem Tag.
+func (a *Api) FindSensors(tag string) (*PrtgSensorsListResponse, error) {
+	cacheKey := fmt.Sprintf("sensors_tag_%s", tag)
+	if cached, ok := a.getCached(cacheKey); ok {
+		var response PrtgSensorsListResponse
+		if err := json.Unmarshal(cached, &response); err == nil {
+			return &response, nil
+		}
+	}
+
 	params := map[string]string{
-		"content":    "values",
-		"id":         objid,
-		"columns":    "value_,datetime",
-		"usecaption": "true",
+		"content": "sensors",
+		"columns": "objid,probe,group,device,sensor,status,tags,type,priority,interval",
+		"count":   "50000",
+	}
+	if tag != "" {
+		params["filter_tags"] = fmt.Sprintf("@tag(%s)", tag)
 	}
 
-	body, err := a.baseExecuteRequest("historicdata.json", params)
+	body, err := a.baseExecuteRequest("table.json", params)
 	if err != nil {
 		return nil, err
 	}
 
-	var response PrtgChannelValueStruct
+	var response PrtgSensorsListResponse
 	if err := json.Unmarshal(body, &response); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	if data, err := json.Marshal(response); err == nil {
+		a.setCached(cacheKey, data)
+	}
 
 	return &response, nil
 }
 
-// GetHistoricalData ruft historische Daten für den angegebenen Sensor und Zeitraum ab.
-func (a *Api) GetHistoricalData(sensorID string, startDate, endDate time.Time) (*PrtgHistoricalDataResponse, error) {
-	// Input validation
-	if sensorID == "" {
-		return nil, fmt.Errorf("invalid query: missing sensor ID")
+// GetSensorDetails liefert die Tabellenzeile eines einzelnen Sensors (u. a. Scanintervall).
+func (a *Api) GetSensorDetails(sensorId string) (*PrtgSensorListItemStruct, error) {
+	if sensorId == "" {
+		return nil, fmt.Errorf("sensor parameter is required")
+	}
+
+	cacheKey := fmt.Sprintf("sensor_%s", sensorId)
+	if cached, ok := a.getCached(cacheKey); ok {
+		var sensor PrtgSensorListItemStruct
+		if err := json.Unmarshal(cached, &sensor); err == nil {
+			return &sensor, nil
+		}
+	}
+
+	params := map[string]string{
+		"content":      "sensors",
+		"columns":      "objid,probe,group,device,sensor,status,tags,type,priority,interval",
+		"filter_objid": sensorId,
 	}
 
-	// Convert to PRTG's timezone (Europe/Berlin)
-	loc, err := time.LoadLocation("Europe/Berlin")
+	body, err := a.baseExecuteRequest("table.json", params)
 	if err != nil {
-		loc = time.Local
+		return nil, err
 	}
 
-	// Add a small buffer to ensure we don't miss data at day boundaries
-	localStartDate := startDate.In(loc).Add(-1 * time.Hour)
-	localEndDate := endDate.In(loc).Add(1 * time.Hour)
+	var response PrtgSensorsListResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(response.Sensors) == 0 {
+		return nil, fmt.Errorf("sensor %s not found", sensorId)
+	}
 
-	// Format dates for PRTG API
-	const format = "2006-01-02-15-04-05"
-	sdate := localStartDate.Format(format)
-	edate := localEndDate.Format(format)
+	sensor := response.Sensors[0]
+	if data, err := json.Marshal(sensor); err == nil {
+		a.setCached(cacheKey, data)
+	}
 
-	// Calculate adjusted time range
-	hours := localEndDate.Sub(localStartDate).Hours()
+	return &sensor, nil
+}
 
-	// Rest of the averaging logic...
-	var avg string
-	switch {
-	case hours <= 12:
-		avg = "0"
-	case hours <= 24:
-		avg = "120"
-	case hours <= 48:
-		avg = "300"
-	case hours <= 96:
-		avg = "600"
-	case hours <= 168:
-		avg = "900"
-	case hours <= 336:
-		avg = "1800"
-	case hours <= 720:
-		avg = "3600"
-	case hours <= 1440:
-		avg = "7200"
-	case hours <= 2880:
-		avg = "14400"
-	case hours <= 4320:
-		avg = "28800"
-	case hours <= 10080:
-		avg = "43200"
-	case hours <= 20160:
-		avg = "57600"	
-	case hours <= 43200:
-		avg = "86400"
-	default:
-		avg = "172800" // 2 days
-	
+/* ====================================== CHANNEL HANDLER ======================================= */
+// GetChannels liefert die Kanaltabelle eines Sensors inklusive Einheit, Grenzwerten und Lookup.
+func (a *Api) GetChannels(objid string) (*PrtgChannelListResponse, error) {
+	return a.getChannels(objid, "channels_", a.cacheTime)
+}
+
+// GetLiveChannels liefert die Kanaltabelle wie GetChannels, die letzten Werte werden aber nur
+// kurz (liveCacheTime) zwischengespeichert.
+func (a *Api) GetLiveChannels(objid string) (*PrtgChannelListResponse, error) {
+	return a.getChannels(objid, "live_channels_", a.liveTTL())
+}
+
+func (a *Api) getChannels(objid, keyPrefix string, ttl time.Duration) (*PrtgChannelListResponse, error) {
+	if objid == "" {
+		return nil, fmt.Errorf("sensor parameter is required")
+	}
+
+	cacheKey := keyPrefix + objid
+	if cached, ok := a.getCached(cacheKey); ok {
+		var response PrtgChannelListResponse
+		if err := json.Unmarshal(cached, &response); err == nil {
+			return &response, nil
+		}
 	}
 
 	params := map[string]string{
-		"id":         sensorID,
-		"columns":    "datetime,value_",
-		"avg":        avg,
-		"sdate":      sdate,
-		"edate":      edate,
-		"count":      "50000",
-		"usecaption": "1",
+		"content": "channels",
+		"id":      objid,
+		"columns": "objid,name,lastvalue,unit,limitmaxerror,limitmaxwarning,limitminerror,limitminwarning,valuelookup",
+		"count":   "50000",
 	}
 
-	log.DefaultLogger.Debug("Requesting historical data",
-		"sensorID", sensorID,
-		"startDate", sdate,
-		"endDate", edate,
-		"avg", avg,
-	)
+	body, err := a.baseExecuteRequest("table.json", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response PrtgChannelListResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// Older PRTG versions do not know the unit column, derive it from the formatted last value
+	for i := range response.Channels {
+		if response.Channels[i].Unit == "" {
+			response.Channels[i].Unit = unitFromFormattedValue(response.Channels[i].Lastvalue)
+		}
+	}
+
+	if data, err := json.Marshal(response); err == nil {
+		a.setCachedFor(cacheKey, data, ttl)
+	}
+
+	return &response, nil
+}
+
+/* ====================================== TABLE HANDLER ========================================= */
+// GetTable liefert die Zeilen einer beliebigen table.json-Abfrage (content=groups, devices, ...)
+// als generische Maps. params enthält Spalten, Filter und ggf. die Eltern-ID ("id").
+func (a *Api) GetTable(content string, params map[string]string) ([]map[string]interface{}, error) {
+	return a.getTable(content, params, "table_", a.cacheTime)
+}
+
+// GetLiveTable liefert die Zeilen wie GetTable, aber nur kurz (liveCacheTime) zwischengespeichert,
+// z. B. für letzte Werte und Alarme.
+func (a *Api) GetLiveTable(content string, params map[string]string) ([]map[string]interface{}, error) {
+	return a.getTable(content, params, "live_table_", a.liveTTL())
+}
+
+func (a *Api) getTable(content string, params map[string]string, keyPrefix string, ttl time.Duration) ([]map[string]interface{}, error) {
+	if content == "" {
+		return nil, fmt.Errorf("content parameter is required")
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var cacheKey strings.Builder
+	cacheKey.WriteString(keyPrefix + content)
+	for _, key := range keys {
+		fmt.Fprintf(&cacheKey, "_%s=%s", key, params[key])
+	}
+
+	if cached, ok := a.getCached(cacheKey.String()); ok {
+		var rows []map[string]interface{}
+		if err := json.Unmarshal(cached, &rows); err == nil {
+			return rows, nil
+		}
+	}
+
+	requestParams := map[string]string{
+		"content": content,
+		"count":   "50000",
+	}
+	for key, value := range params {
+		requestParams[key] = value
+	}
+
+	body, err := a.baseExecuteRequest("table.json", requestParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	// Die Zeilen stehen unter dem Namen des Inhalts, bei einigen Inhalten (z. B. probenode)
+	// weicht er ab; dann wird das erste Array der Antwort verwendet.
+	rows := make([]map[string]interface{}, 0)
+	raw, ok := response[content]
+	if !ok {
+		for key, value := range response {
+			if key != "prtg-version" && key != "treesize" && strings.HasPrefix(strings.TrimSpace(string(value)), "[") {
+				raw, ok = value, true
+				break
+			}
+		}
+	}
+	if ok {
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", content, err)
+		}
+	}
+
+	if data, err := json.Marshal(rows); err == nil {
+		a.setCachedFor(cacheKey.String(), data, ttl)
+	}
+
+	return rows, nil
+}
+
+/* ====================================== LOOKUP HANDLER ======================================== */
+// GetLookup lädt die Definition einer Werte-Lookup-Datei (z. B. prtg.standardlookups.yesno.stateyesok).
+func (a *Api) GetLookup(lookupId string) (*PrtgValueLookup, error) {
+	if lookupId == "" {
+		return nil, fmt.Errorf("lookup parameter is required")
+	}
+
+	cacheKey := fmt.Sprintf("lookup_%s", lookupId)
+	if cached, ok := a.getCached(cacheKey); ok {
+		var response PrtgValueLookup
+		if err := json.Unmarshal(cached, &response); err == nil {
+			return &response, nil
+		}
+	}
+
+	body, err := a.baseExecuteRequest("getlookup.htm", map[string]string{"id": lookupId})
+	if err != nil {
+		return nil, err
+	}
+
+	var response PrtgValueLookup
+	if err := xml.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse lookup definition: %w", err)
+	}
+
+	if data, err := json.Marshal(response); err == nil {
+		a.setCached(cacheKey, data)
+	}
+
+	return &response, nil
+}
+
+// unitFromFormattedValue extracts the unit suffix of a formatted PRTG value like "3,2 Mbit/s".
+func unitFromFormattedValue(value string) string {
+	value = strings.TrimSpace(value)
+	idx := strings.LastIndex(value, " ")
+	if idx < 0 {
+		return ""
+	}
+	unit := strings.TrimSpace(value[idx+1:])
+	if strings.ContainsAny(unit, "0123456789") {
+		return ""
+	}
+	return unit
+}
+
+// GetHistoricalData ruft historische Daten für den angegebenen Sensor und Zeitraum ab.
+// sdate/edate werden in loc formatiert, nil verwendet die Zeitzone der Datenquelle.
+// Lange Zeiträume werden in Abschnitten abgerufen (siehe fetchHistoricChunks).
+func (a *Api) GetHistoricalData(sensorID string, startDate, endDate time.Time, loc *time.Location) (*PrtgHistoricalDataResponse, error) {
+	// Input validation
+	if sensorID == "" {
+		return nil, fmt.Errorf("invalid query: missing sensor ID")
+	}
+
+	// Convert to the PRTG timezone
+	if loc == nil {
+		loc = fallbackLocation()
+	}
+
+	// Add a small buffer to ensure we don't miss data at day boundaries
+	localStartDate := startDate.In(loc).Add(-1 * time.Hour)
+	localEndDate := endDate.In(loc).Add(1 * time.Hour)
+
+	// The averaging is chosen for the whole range so all chunks share the same resolution
+	avg := averagingInterval(startDate, endDate)
 
 	// Use cacheTime for response caching
-	cacheKey := fmt.Sprintf("hist_%s_%s_%s", sensorID, startDate.Format(time.RFC3339), endDate.Format(time.RFC3339))
+	cacheKey := fmt.Sprintf("hist_%s_%s_%s_%s", sensorID, startDate.Format(time.RFC3339), endDate.Format(time.RFC3339), loc.String())
 
 	// Check cache
 	a.cacheMu.RLock()
@@ -315,19 +532,13 @@ func (a *Api) GetHistoricalData(sensorID string, startDate, endDate time.Time) (
 		if err := json.Unmarshal(cached.data, &response); err == nil {
 			return &response, nil
 		}
+	} else {
+		a.cacheMu.RUnlock()
 	}
-	a.cacheMu.RUnlock()
 
-	// Make API request
-	body, err := a.baseExecuteRequest("historicdata.json", params)
+	response, err := a.fetchHistoricChunks(sensorID, localStartDate, localEndDate, avg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch historical data: %w", err)
-	}
-
-	// Parse response
-	var response PrtgHistoricalDataResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, err
 	}
 
 	// Validate response
@@ -337,24 +548,100 @@ func (a *Api) GetHistoricalData(sensorID string, startDate, endDate time.Time) (
 			"startDate", startDate,
 			"endDate", endDate,
 		)
-		return &response, nil // Return empty response instead of error
+		return response, nil // Return empty response instead of error
 	}
 
 	// Cache the response
-	if len(response.HistData) > 0 {
-		a.cacheMu.Lock()
-		if data, err := json.Marshal(response); err == nil {
-			a.cache[cacheKey] = cacheItem{
-				data:   data,
-				expiry: time.Now().Add(a.cacheTime),
-			}
+	a.cacheMu.Lock()
+	if data, err := json.Marshal(response); err == nil {
+		a.cache[cacheKey] = cacheItem{
+			data:   data,
+			expiry: time.Now().Add(a.cacheTime),
 		}
-		a.cacheMu.Unlock()
 	}
+	a.cacheMu.Unlock()
+
+	return response, nil
+}
+
+// getHistoricChunk ruft einen einzelnen Abschnitt von historicdata.json ab.
+// from und to müssen bereits in der PRTG-Zeitzone liegen.
+func (a *Api) getHistoricChunk(sensorID string, from, to time.Time, avg time.Duration) (*PrtgHistoricalDataResponse, error) {
+	// Format dates for PRTG API
+	const format = "2006-01-02-15-04-05"
+	sdate := from.Format(format)
+	edate := to.Format(format)
+	avgSeconds := strconv.FormatInt(int64(avg.Seconds()), 10)
+
+	params := map[string]string{
+		"id":      sensorID,
+		"columns": "datetime,value_",
+		"avg":     avgSeconds,
+		"sdate":   sdate,
+		"edate":   edate,
+		"count":   strconv.Itoa(historicRowLimit),
+	}
+
+	log.DefaultLogger.Debug("Requesting historical data",
+		"sensorID", sensorID,
+		"startDate", sdate,
+		"endDate", edate,
+		"avg", avgSeconds,
+	)
 
+	// Make API request
+	body, err := a.baseExecuteRequest("historicdata.json", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch historical data: %w", err)
+	}
+
+	// Parse response
+	var response PrtgHistoricalDataResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
 	return &response, nil
 }
 
+// averagingInterval liefert die PRTG-Mittelwertbildung (avg) für den angefragten Zeitraum.
+// Der Zeitraum wird wie in GetHistoricalData um je eine Stunde gepuffert.
+func averagingInterval(startDate, endDate time.Time) time.Duration {
+	hours := endDate.Sub(startDate).Hours() + 2
+
+	var avg int64
+	switch {
+	case hours <= 12:
+		avg = 0
+	case hours <= 24:
+		avg = 120
+	case hours <= 48:
+		avg = 300
+	case hours <= 96:
+		avg = 600
+	case hours <= 168:
+		avg = 900
+	case hours <= 336:
+		avg = 1800
+	case hours <= 720:
+		avg = 3600
+	case hours <= 1440:
+		avg = 7200
+	case hours <= 2880:
+		avg = 14400
+	case hours <= 4320:
+		avg = 28800
+	case hours <= 10080:
+		avg = 43200
+	case hours <= 20160:
+		avg = 57600
+	case hours <= 43200:
+		avg = 86400
+	default:
+		avg = 172800 // 2 days
+	}
+	return time.Duration(avg) * time.Second
+}
+
 /* ====================================== MANUAL METHOD HANDLER ================================= */
 func (a *Api) ExecuteManualMethod(method string, objectId string) (*PrtgManualMethodResponse, error) {
 	params := map[string]string{}
@@ -417,19 +704,20 @@ func flattenJSON(prefix string, data interface{}, result *[]KeyValue) {
 }
 
 /* ====================================== ANNOTATION HANDLER ====================================== */
-func (a *Api) GetAnnotationData(query *AnnotationQuery) (*AnnotationResponse, error) {
+// GetAnnotationData liefert die historischen Werte eines Sensors als Annotationen, Zeitstempel in loc.
+func (a *Api) GetAnnotationData(query *AnnotationQuery, loc *time.Location) (*AnnotationResponse, error) {
 	// Get time range
 	fromTime := time.Unix(0, query.From*int64(time.Millisecond))
 	toTime := time.Unix(0, query.To*int64(time.Millisecond))
 
-	histData, err := a.GetHistoricalData(query.SensorID, fromTime, toTime)
+	histData, err := a.GetHistoricalData(query.SensorID, fromTime, toTime, loc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch historical data for annotations: %w", err)
 	}
 
 	annotations := make([]Annotation, 0)
 	for i, data := range histData.HistData {
-		t, _, err := parsePRTGDateTime(data.Datetime)
+		t, err := parsePRTGTimestamp(data.DatetimeRAW, data.Datetime, loc)
 		if err != nil {
 			continue
 		}
@@ -464,4 +752,4 @@ func (a *Api) GetAnnotationData(query *AnnotationQuery) (*AnnotationResponse, er
 // Add GetCacheTime method to implement PRTGAPI interface
 func (a *Api) GetCacheTime() time.Duration {
 	return a.cacheTime
-}
\ No newline at end of file
+}
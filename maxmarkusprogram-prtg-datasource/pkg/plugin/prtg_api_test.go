@@ -0,0 +1,47 @@
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLiveTableCache(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"sensors":[{"objid":1001,"lastvalue_raw":15}]}`))
+	}))
+	defer server.Close()
+
+	api := NewApi(server.URL, "token", time.Minute, 5*time.Second)
+	params := map[string]string{"columns": "objid,lastvalue"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := api.GetTable("sensors", params); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("cached table made %d requests, want 1", got)
+	}
+
+	// Live values are not served from the long lived table cache
+	rows, err := api.GetLiveTable("sensors", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := requests.Load(); got != 2 || len(rows) != 1 {
+		t.Fatalf("live table made %d requests and returned %d rows, want 2 and 1", got, len(rows))
+	}
+
+	limit := time.Now().Add(liveCacheTime)
+	for key, item := range api.cache {
+		if strings.HasPrefix(key, "live_") && item.expiry.After(limit) {
+			t.Errorf("live entry %s expires at %v, after %v", key, item.expiry, limit)
+		}
+	}
+}
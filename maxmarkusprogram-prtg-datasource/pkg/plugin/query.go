@@ -3,6 +3,7 @@ package plugin
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -26,11 +27,13 @@ func (d *Datasource) query(ctx context.Context, pCtx backend.PluginContext, quer
 		qm.CacheTime = 6000 // Default to 6 seconds if not specified
 	}
 
-	// Generate cache key including time range
+	// Generate cache key including time range. Alert rules of different sensors often share
+	// the refId "A", so the query itself is part of the key.
 	cacheKey := QueryCacheKey{
-		RefID:     query.RefID,
-		QueryType: query.QueryType,
-		TimeRange: fmt.Sprintf("%v-%v", query.TimeRange.From.Unix(), query.TimeRange.To.Unix()),
+		RefID:      query.RefID,
+		QueryType:  query.QueryType,
+		TimeRange:  fmt.Sprintf("%v-%v", query.TimeRange.From.Unix(), query.TimeRange.To.Unix()),
+		Parameters: fmt.Sprintf("%d_%s", query.MaxDataPoints, query.JSON),
 	}
 
 	// Check cache with proper expiration
@@ -84,15 +87,26 @@ func (d *Datasource) executeQuery(ctx context.Context, pCtx backend.PluginContex
 		recordError(span, err, "Failed to parse query")
 		return backend.ErrDataResponse(backend.StatusBadRequest, "failed to parse query")
 	}
+	if query.MaxDataPoints > 0 {
+		qm.MaxDataPoints = query.MaxDataPoints
+	}
+	// Alert rules and older saved queries may only set one of the two query types
+	if qm.QueryType == "" {
+		qm.QueryType = query.QueryType
+	}
+	if qm.QueryType == "" {
+		qm.QueryType = "metrics"
+	}
+
 	// Generate stable cache key that includes time range and refId
 	cacheKey := QueryCacheKey{
 		RefID:      query.RefID,
 		QueryType:  query.QueryType,
 		SensorID:   qm.SensorId,
-		Channel:    strings.Join(qm.ChannelArray, ","),
+		Channel:    strings.Join(qm.ChannelIds, ",") + "|" + strings.Join(qm.ChannelArray, ","),
 		TimeRange:  fmt.Sprintf("%v-%v", query.TimeRange.From.Unix(), query.TimeRange.To.Unix()),
 		Property:   qm.Property,
-		Parameters: fmt.Sprintf("%s_%s_%s", qm.Group, qm.Device, qm.Sensor), // Add unique identifiers
+		Parameters: fmt.Sprintf("%d_%s", qm.MaxDataPoints, query.JSON), // The whole query, every option changes the result
 	}
 
 	// Get cache duration from API
@@ -110,6 +124,10 @@ func (d *Datasource) executeQuery(ctx context.Context, pCtx backend.PluginContex
 	default:
 		cacheDuration = cacheTime
 	}
+	// Current values must not outlive the short cache of the PRTG requests
+	if qm.QueryType == "lastvalue" || qm.QueryType == "alarms" {
+		cacheDuration = min(cacheDuration, liveCacheTime)
+	}
 
 	// Use String() method to convert cacheKey to string
 	cacheKeyStr := cacheKey.String()
@@ -140,7 +158,7 @@ func (d *Datasource) executeQuery(ctx context.Context, pCtx backend.PluginContex
 	var response backend.DataResponse
 	switch qm.QueryType {
 	case "metrics":
-		if qm.Channel == "" && len(qm.ChannelArray) == 0 {
+		if qm.Channel == "" && len(qm.ChannelArray) == 0 && len(qm.ChannelIds) == 0 && qm.Expression == "" {
 			d.logger.Error("Channel selection required for metrics query")
 			d.metrics.IncError("missing_channel")
 			return backend.ErrDataResponse(backend.StatusBadRequest, "channel selection required")
@@ -158,6 +176,24 @@ func (d *Datasource) executeQuery(ctx context.Context, pCtx backend.PluginContex
 			d.cacheMutex.Unlock()
 		}
 
+	case "search":
+		response = d.handleSearchQuery(ctx, qm, query.TimeRange, fmt.Sprintf("search_%s", query.RefID))
+
+	case "lastvalue":
+		response = d.handleLastValueQuery(ctx, qm, fmt.Sprintf("lastvalue_%s", query.RefID))
+
+	case "logs":
+		response = d.handleLogsQuery(ctx, qm, query.TimeRange, fmt.Sprintf("logs_%s", query.RefID))
+
+	case "alarms":
+		response = d.handleAlarmsQuery(ctx, qm, fmt.Sprintf("alarms_%s", query.RefID))
+
+	case "table":
+		response = d.handleTableQuery(ctx, qm, fmt.Sprintf("table_%s", query.RefID))
+
+	case "variables":
+		response = d.handleVariableQuery(ctx, qm.Variable, fmt.Sprintf("variables_%s", query.RefID))
+
 	case "manual":
 		d.logger.Debug("Executing manual query",
 			"method", qm.ManualMethod,
@@ -174,11 +210,7 @@ func (d *Datasource) executeQuery(ctx context.Context, pCtx backend.PluginContex
 			"refID", query.RefID,
 		)
 		d.metrics.IncError("unknown_query_type")
-		return backend.DataResponse{
-			Frames: []*data.Frame{
-				data.NewFrame(fmt.Sprintf("unknown_%s", query.RefID)),
-			},
-		}
+		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("unknown query type '%s'", qm.QueryType))
 	}
 
 	// Cache response with proper duration
@@ -228,205 +260,159 @@ func (d *Datasource) handleMetricsQuery(ctx context.Context, qm queryModel, time
 		Frames: make([]*data.Frame, 0),
 	}
 
-	// Fetch historical data once for all channels
-	historicalData, err := d.api.GetHistoricalData(qm.SensorId, timeRange.From.UTC(), timeRange.To.UTC())
+	// Several sensors, e.g. from a multi-value variable, give one frame per series
+	sensors, multi, err := d.multiSensorIds(qm)
 	if err != nil {
-		d.logger.Error("Failed to fetch historical data",
-			"error", err,
-			"sensorId", qm.SensorId,
-		)
-		d.metrics.IncError("historical_data_fetch")
-		recordError(span, err, "Failed to fetch historical data")
-		return backend.ErrDataResponse(backend.StatusInternal, "failed to fetch data")
+		recordError(span, err, "Failed to resolve sensors")
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
 	}
-
-	// Check if we have channels to process
-	if len(qm.ChannelArray) == 0 && qm.Channel == "" {
-		d.logger.Error("No channels specified")
-		d.metrics.IncError("missing_channel")
-		return backend.ErrDataResponse(backend.StatusBadRequest, "channel selection required")
+	if multi {
+		return d.multiSensorResponse(qm, sensors, timeRange, baseFrameName, "multi-sensor")
 	}
-	// Use ChannelArray if available, otherwise fall back to single channel
-	channels := qm.ChannelArray
-	if len(channels) == 0 && qm.Channel != "" {
-		channels = []string{qm.Channel}
+	if ids := qm.sensorIds(); len(ids) == 1 {
+		qm.SensorId = ids[0] // e.g. "{1234}" of a variable with a single value
 	}
-
-	// If multiple channels are selected, create a single frame with multiple series
-	if len(channels) > 1 {
-		// Create a single frame with time field and multiple value fields
-		timesM := make([]time.Time, 0)
-		channelData := make(map[string][]float64)
-		
-		// Initialize channel data maps
-		for _, channelName := range channels {
-			channelData[channelName] = make([]float64, 0)
+	if qm.Expression == "" && len(qm.AdhocFilters) > 0 {
+		matches, err := d.sensorMatchesAdhoc(qm.SensorId, qm.AdhocFilters)
+		if err != nil {
+			recordError(span, err, "Failed to apply ad-hoc filters")
+			return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+		}
+		if !matches {
+			frame := data.NewFrame(fmt.Sprintf("%s_empty", baseFrameName))
+			frame.AppendNotices(data.Notice{
+				Severity: data.NoticeSeverityInfo,
+				Text:     fmt.Sprintf("Sensor %s does not match the ad-hoc filters", qm.SensorId),
+			})
+			return backend.DataResponse{Frames: []*data.Frame{frame}}
 		}
+	}
 
-		if historicalData != nil && len(historicalData.HistData) > 0 {
-			for _, item := range historicalData.HistData {
-				parsedTime, _, err := parsePRTGDateTime(item.Datetime)
-				if err != nil {
-					continue
-				}
+	// Load the channels of the sensor or evaluate the expression over several sensors
+	var result *metricResult
+	if qm.Expression != "" {
+		result, err = d.loadExpressionTable(qm, timeRange)
+	} else {
+		result, err = d.loadMetricTable(qm, timeRange)
+	}
+	if err != nil {
+		recordError(span, err, "Failed to load metrics")
+		return queryErrorResponse(err)
+	}
+	table := result.table
+	expectedSpacing := result.spacing
 
-				// Check if we have data for any of the requested channels
-				hasData := false
-				tempValues := make(map[string]float64)
-				
-				for _, channelName := range channels {
-					if val, exists := item.Value[channelName]; exists {
-						var floatVal float64
-						switch v := val.(type) {
-						case float64:
-							floatVal = v
-						case string:
-							if parsed, err := strconv.ParseFloat(v, 64); err == nil {
-								floatVal = parsed
-							} else {
-								continue
-							}
-						default:
-							continue
-						}
-						tempValues[channelName] = floatVal
-						hasData = true
-					}
-				}
+	channels := make([]string, 0, len(table.Columns))
+	for _, column := range table.Columns {
+		channels = append(channels, column.Selection.Name)
+	}
 
-				if hasData {
-					timesM = append(timesM, parsedTime)
-					// Add values for each channel (use 0 or NaN for missing values)
-					for _, channelName := range channels {
-						if val, exists := tempValues[channelName]; exists {
-							channelData[channelName] = append(channelData[channelName], val)
-						} else {
-							// Use NaN for missing values to maintain alignment
-							channelData[channelName] = append(channelData[channelName], 0)
-						}
-					}
-				}
-			}
-		}
+	// Labels identify the series for legends, alert dimensions and joins.
+	// Expression results combine several sensors and only carry their own name.
+	labels := data.Labels{}
+	if qm.Expression == "" {
+		labels = d.queryLabels(qm)
+	}
 
+	// Reduce the points to what the panel can show, before null filling so buckets only see real values
+	if rows := len(table.Times); table.downsample(qm.Downsample, int(qm.MaxDataPoints)) {
+		d.logger.Debug("Downsampled historical data",
+			"sensorId", qm.SensorId,
+			"mode", qm.Downsample,
+			"rows", rows,
+			"points", len(table.Times),
+		)
+	}
+	table.fillNulls(qm.NullValueMode)
+
+	// Interval bounds are extra time fields, a frame with them is no valid time series wide frame
+	// and keeps its type unset, so Grafana handles it like a table.
+	withBounds := qm.IncludeIntervalBounds && table.hasIntervalBounds()
+	frameType, frameTypeVersion := data.FrameTypeTimeSeriesWide, data.FrameTypeVersion{0, 1}
+	if withBounds {
+		frameType, frameTypeVersion = data.FrameTypeUnknown, data.FrameTypeVersion{}
+	}
+
+	// If multiple channels are selected, create a single frame with multiple series
+	if len(table.Columns) > 1 {
 		// Create frame with time field
 		fields := []*data.Field{
-			data.NewField("Time", nil, timesM),
+			data.NewField("Time", nil, table.Times),
 		}
 		// Add a field for each channel
-		for _, channelName := range channels {
-			displayName := channelName
-			if qm.IncludeGroupName && qm.Group != "" {
-				displayName = fmt.Sprintf("%s - %s", qm.Group, displayName)
-			}
-			if qm.IncludeDeviceName && qm.Device != "" {
-				displayName = fmt.Sprintf("%s - %s", qm.Device, displayName)
-			}
-			if qm.IncludeSensorName && qm.Sensor != "" {
-				displayName = fmt.Sprintf("%s - %s", qm.Sensor, displayName)
-			}
-
-			field := data.NewField(channelName, nil, channelData[channelName]).SetConfig(&data.FieldConfig{
-				DisplayName: displayName,
-				Custom: map[string]interface{}{
-					"refId":     baseFrameName,
-					"channel":   channelName,
-					"queryType": "multi-channel",
-				},
-			})
-			fields = append(fields, field)
+		for _, column := range table.Columns {
+			fieldConfig := d.metricFieldConfig(qm, column, baseFrameName, "multi-channel")
+			fields = append(fields, data.NewField(column.Selection.Name, channelLabels(labels, column.Selection), column.Values).SetConfig(fieldConfig))
+		}
+		if withBounds {
+			fields = append(fields, intervalBoundFields(table)...)
 		}
 		// Create single frame with all channels
 		frame := data.NewFrame(fmt.Sprintf("%s_multi", baseFrameName), fields...)
 		frame.Meta = &data.FrameMeta{
-			Type: data.FrameTypeTimeSeriesMulti,
+			Type:        frameType,
+			TypeVersion: frameTypeVersion,
 			Custom: map[string]interface{}{
-				"from":       timeRange.From.UnixMilli(),
-				"to":         timeRange.To.UnixMilli(),
-				"channels":   channels,
-				"stable":     true,
-				"duration":   timeRange.To.Sub(timeRange.From).String(),
-				"timezone":   "UTC",
-				"queryType":  "multi-channel",
-				"refId":      baseFrameName, // Keep refId stable
+				"from":      timeRange.From.UnixMilli(),
+				"to":        timeRange.To.UnixMilli(),
+				"channels":  channels,
+				"stable":    true,
+				"duration":  timeRange.To.Sub(timeRange.From).String(),
+				"timezone":  "UTC",
+				"queryType": "multi-channel",
+				"interval":  expectedSpacing.Milliseconds(),
+				"alignment": timestampAlignment(qm.TimestampAlignment),
+				"refId":     baseFrameName, // Keep refId stable
 			},
 		}
 
 		response.Frames = append(response.Frames, frame)
 	} else {
-		// Single channel - use existing logic
-		channelName := channels[0]
-		timesM := make([]time.Time, 0)
-		valuesM := make([]float64, 0)
-
-		if historicalData != nil && len(historicalData.HistData) > 0 {
-			for _, item := range historicalData.HistData {
-				parsedTime, _, err := parsePRTGDateTime(item.Datetime)
-				if err != nil {
-					continue
-				}
+		// Single channel
+		column := table.Columns[0]
+		fieldConfig := d.metricFieldConfig(qm, column, baseFrameName, "single-channel")
 
-				if val, exists := item.Value[channelName]; exists {
-					var floatVal float64
-					switch v := val.(type) {
-					case float64:
-						floatVal = v
-					case string:
-						if parsed, err := strconv.ParseFloat(v, 64); err == nil {
-							floatVal = parsed
-						} else {
-							continue
-						}
-					default:
-						continue
-					}
-
-					timesM = append(timesM, parsedTime)
-					valuesM = append(valuesM, floatVal)
-				}
-			}
-		}
-
-		// Build display name with optional prefixes
-		displayName := channelName
-		if qm.IncludeGroupName && qm.Group != "" {
-			displayName = fmt.Sprintf("%s - %s", qm.Group, displayName)
-		}
-		if qm.IncludeDeviceName && qm.Device != "" {
-			displayName = fmt.Sprintf("%s - %s", qm.Device, displayName)
-		}
-		if qm.IncludeSensorName && qm.Sensor != "" {
-			displayName = fmt.Sprintf("%s - %s", qm.Sensor, displayName)
-		}		// Create frame for single channel
+		// Create frame for single channel
 		frame := data.NewFrame(fmt.Sprintf("%s_single", baseFrameName),
-			data.NewField("Time", nil, timesM),
-			data.NewField("Value", nil, valuesM).SetConfig(&data.FieldConfig{
-				DisplayName: displayName,
-				Custom: map[string]interface{}{
-					"refId":     baseFrameName,
-					"channel":   channelName,
-					"queryType": "single-channel",
-				},
-			}),
+			data.NewField("Time", nil, table.Times),
+			data.NewField("Value", channelLabels(labels, column.Selection), column.Values).SetConfig(fieldConfig),
 		)
+		if withBounds {
+			frame.Fields = append(frame.Fields, intervalBoundFields(table)...)
+		}
 
 		frame.Meta = &data.FrameMeta{
-			Type: data.FrameTypeTimeSeriesMulti,
+			Type:        frameType,
+			TypeVersion: frameTypeVersion,
 			Custom: map[string]interface{}{
-				"from":       timeRange.From.UnixMilli(),
-				"to":         timeRange.To.UnixMilli(),
-				"channel":    channelName,
-				"stable":     true,
-				"duration":   timeRange.To.Sub(timeRange.From).String(),
-				"timezone":   "UTC",
-				"queryType":  "single-channel",
-				"refId":      baseFrameName, // Keep refId stable
+				"from":      timeRange.From.UnixMilli(),
+				"to":        timeRange.To.UnixMilli(),
+				"channel":   column.Selection.Name,
+				"channelId": column.Selection.ID,
+				"stable":    true,
+				"duration":  timeRange.To.Sub(timeRange.From).String(),
+				"timezone":  "UTC",
+				"queryType": "single-channel",
+				"interval":  expectedSpacing.Milliseconds(),
+				"alignment": timestampAlignment(qm.TimestampAlignment),
+				"refId":     baseFrameName, // Keep refId stable
 			},
 		}
 
 		response.Frames = append(response.Frames, frame)
 	}
 
+	// Expressions combine several sensors, ad-hoc filters cannot select some of them
+	if qm.Expression != "" && len(qm.AdhocFilters) > 0 {
+		result.notices = append(result.notices, data.Notice{
+			Severity: data.NoticeSeverityInfo,
+			Text:     "Ad-hoc filters ignored, they do not apply to expressions",
+		})
+	}
+	for _, frame := range response.Frames {
+		frame.AppendNotices(result.notices...)
+	}
+
 	// If no frames were created, add an empty frame
 	if len(response.Frames) == 0 {
 		response.Frames = append(response.Frames, data.NewFrame(fmt.Sprintf("%s_empty", baseFrameName)))
@@ -438,6 +424,186 @@ func (d *Datasource) handleMetricsQuery(ctx context.Context, qm queryModel, time
 	return response
 }
 
+// metricResult is the parsed historic data of a metrics query before downsampling
+type metricResult struct {
+	table   *metricTable
+	spacing time.Duration // expected time between two rows
+	notices []data.Notice
+}
+
+// queryError carries the status a failed query step is reported with
+type queryError struct {
+	status  backend.Status
+	message string
+	err     error // cause, if any
+}
+
+func (e *queryError) Error() string {
+	return e.message
+}
+
+func (e *queryError) Unwrap() error {
+	return e.err
+}
+
+// queryErrorResponse converts an error into a data response, keeping the status of a queryError
+func queryErrorResponse(err error) backend.DataResponse {
+	var qe *queryError
+	if errors.As(err, &qe) {
+		return backend.ErrDataResponse(qe.status, qe.message)
+	}
+	return backend.ErrDataResponse(backend.StatusInternal, err.Error())
+}
+
+// loadMetricTable fetches and parses the historic data of the selected channels of one sensor:
+// channel resolution, timestamp alignment, transforms and gap detection.
+func (d *Datasource) loadMetricTable(qm queryModel, timeRange backend.TimeRange) (*metricResult, error) {
+	// The query timezone overrides the datasource timezone
+	loc, err := d.queryLocation(qm.Timezone)
+	if err != nil {
+		d.metrics.IncError("invalid_timezone")
+		return nil, &queryError{status: backend.StatusBadRequest, message: err.Error()}
+	}
+
+	// Check if we have channels to process
+	if len(qm.ChannelIds) == 0 && len(qm.ChannelArray) == 0 && qm.Channel == "" {
+		d.logger.Error("No channels specified")
+		d.metrics.IncError("missing_channel")
+		return nil, &queryError{status: backend.StatusBadRequest, message: "channel selection required"}
+	}
+
+	// Fetch historical data once for all channels
+	historicalData, err := d.api.GetHistoricalData(qm.SensorId, timeRange.From.UTC(), timeRange.To.UTC(), loc)
+	if err != nil {
+		d.logger.Error("Failed to fetch historical data",
+			"error", err,
+			"sensorId", qm.SensorId,
+		)
+		d.metrics.IncError("historical_data_fetch")
+		return nil, &queryError{status: backend.StatusInternal, message: fmt.Sprintf("failed to fetch data: %v", err), err: err}
+	}
+
+	// The channel table maps channel ids to the current captions and provides unit and limits
+	channelList, err := d.api.GetChannels(qm.SensorId)
+	if err != nil {
+		d.logger.Warn("Failed to fetch channel table, falling back to channel captions",
+			"error", err,
+			"sensorId", qm.SensorId,
+		)
+		channelList = nil
+	}
+
+	selections, err := resolveChannelSelections(qm, channelList)
+	if err != nil {
+		d.logger.Error("Failed to resolve channels", "error", err, "sensorId", qm.SensorId)
+		d.metrics.IncError("missing_channel")
+		return nil, &queryError{status: backend.StatusBadRequest, message: err.Error(), err: err}
+	}
+	mapHistoricColumns(historicalData, selections)
+
+	// Parse all channels onto a common time axis, missing values stay null.
+	// Values which exist in PRTG but could not be parsed are reported as frame notice
+	table, droppedValues := parseMetricTable(historicalData, selections, loc, d.decimalSeparator)
+
+	// PRTG stamps averaged rows with the interval end, shift them if another alignment is requested
+	table.alignTimestamps(qm.TimestampAlignment, averagingInterval(timeRange.From, timeRange.To))
+
+	// Per-channel transforms run on the parsed values, so they also apply in alerting
+	if err := table.applyTransforms(qm.Transforms); err != nil {
+		d.logger.Error("Failed to apply transforms", "error", err, "sensorId", qm.SensorId)
+		d.metrics.IncError("invalid_transform")
+		return nil, &queryError{status: backend.StatusBadRequest, message: err.Error()}
+	}
+
+	// Make outages visible: PRTG has no rows while a sensor or probe was down
+	expectedSpacing := d.expectedSampleSpacing(qm.SensorId, timeRange)
+	if gaps := table.insertGaps(expectedSpacing); gaps > 0 {
+		d.logger.Debug("Inserted gaps into historical data",
+			"sensorId", qm.SensorId,
+			"gaps", gaps,
+			"expectedSpacing", expectedSpacing,
+		)
+	}
+
+	notices := make([]data.Notice, 0)
+	if notice := truncatedHistoricNotice(historicalData); notice != nil {
+		d.logger.Warn("Historical data truncated", "sensorId", qm.SensorId, "chunks", historicalData.TruncatedChunks)
+		notices = append(notices, *notice)
+	}
+	if notice := droppedValuesNotice(droppedValues); notice != nil {
+		d.logger.Warn("Dropped unparsable values", "sensorId", qm.SensorId, "dropped", droppedValues)
+		notices = append(notices, *notice)
+	}
+
+	return &metricResult{
+		table:   table,
+		spacing: expectedSpacing,
+		notices: notices,
+	}, nil
+}
+
+// expectedSampleSpacing returns the time PRTG should have between two rows of historic
+// data: the scanning interval of the sensor or the averaging interval, whichever is larger.
+func (d *Datasource) expectedSampleSpacing(sensorId string, timeRange backend.TimeRange) time.Duration {
+	spacing := averagingInterval(timeRange.From, timeRange.To)
+
+	sensor, err := d.api.GetSensorDetails(sensorId)
+	if err != nil {
+		d.logger.Debug("Failed to fetch sensor scanning interval", "error", err, "sensorId", sensorId)
+		return spacing
+	}
+	if interval := time.Duration(sensor.IntervalRAW) * time.Second; interval > spacing {
+		spacing = interval
+	}
+	return spacing
+}
+
+// timestampAlignment returns the effective timestamp alignment of a query
+func timestampAlignment(mode string) string {
+	switch mode {
+	case TimestampAlignmentStart, TimestampAlignmentMidpoint:
+		return mode
+	default:
+		return TimestampAlignmentEnd
+	}
+}
+
+// intervalBoundFields returns the start and end of the averaging interval of every row as
+// nullable time fields. They come after the value fields so the first value field stays at index 1.
+func intervalBoundFields(table *metricTable) []*data.Field {
+	return []*data.Field{
+		data.NewField("Interval start", nil, table.IntervalStarts),
+		data.NewField("Interval end", nil, table.IntervalEnds),
+	}
+}
+
+// metricFieldConfig builds the config of a channel value field: default display name, channel
+// metadata and, unless disabled, unit, thresholds and value mappings from PRTG.
+// Transformed columns only keep the unit, limits and lookups refer to the original values.
+func (d *Datasource) metricFieldConfig(qm queryModel, column *metricColumn, baseFrameName, queryType string) *data.FieldConfig {
+	sel := column.Selection
+
+	fieldConfig := &data.FieldConfig{
+		DisplayNameFromDS: legacyDisplayName(qm, sel.Name),
+		Custom: map[string]interface{}{
+			"refId":     baseFrameName,
+			"channel":   sel.Name,
+			"channelId": sel.ID,
+			"queryType": queryType,
+		},
+	}
+	switch {
+	case qm.DisableChannelConfig:
+		fieldConfig.Unit = column.Unit
+	case column.Transformed:
+		fieldConfig.Unit = column.unit()
+	default:
+		applyChannelFieldConfig(fieldConfig, sel.Info, d.decimalSeparator)
+		fieldConfig.Mappings = d.channelValueMappings(sel.Info)
+	}
+	return fieldConfig
+}
+
 /* =================================== MANUAL QUERY HANDLER =================================== */
 func (d *Datasource) handleManualQuery(qm queryModel, timeRange backend.TimeRange, frameBaseName string) backend.DataResponse {
 	d.logger.Debug("Processing manual query",
@@ -489,8 +655,7 @@ func (d *Datasource) handleManualQuery(qm queryModel, timeRange backend.TimeRang
 			DisplayName: "Value",
 		}),
 	).SetMeta(&data.FrameMeta{
-		Type:   data.FrameTypeTimeSeriesWide,
-		Custom: response.Manuel,
+		Custom: response.Manuel, // Key/value table, no time series
 	})
 
 	return backend.DataResponse{
@@ -519,6 +684,34 @@ func (d *Datasource) handlePropertyQuery(ctx context.Context, qm queryModel, pro
 		)
 	}
 
+	loc, err := d.queryLocation(qm.Timezone)
+	if err != nil {
+		d.metrics.IncError("invalid_timezone")
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	// Several objects give one frame each, a single object id is resolved to its current names
+	switch ids := qm.propertyObjectIds(property); {
+	case len(ids) > 1:
+		return d.handleMultiObjectPropertyQuery(ctx, qm, ids, property, filterProperty, baseFrameName)
+	case len(ids) == 1:
+		objectQuery, err := d.objectQuery(qm, property, ids[0])
+		if err != nil {
+			d.logger.Debug("Failed to resolve object id, using stored names", "id", ids[0], "error", err)
+			qm.GroupId, qm.DeviceId, qm.SensorId = "", "", ""
+		} else {
+			qm = objectQuery
+		}
+	}
+
+	// Ad-hoc filters decide whether the object is shown
+	adhoc, err := newAdhocMatchers(qm.AdhocFilters)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	adhoc, ignored := splitAdhocFilters(adhoc, propertyAdhocColumns[property])
+	filtered := false
+
 	var timesRT []time.Time
 	var valuesRT []interface{}
 
@@ -529,8 +722,12 @@ func (d *Datasource) handlePropertyQuery(ctx context.Context, qm queryModel, pro
 			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("API request failed: %v", err))
 		}
 		for _, g := range groups.Groups {
-			if g.Group == qm.Group {
-				timestamp, _, err := parsePRTGDateTime(g.Datetime)
+			if matchesObject(g.ObjectId, g.Group, qm.GroupId, qm.Group) {
+				if !matchesAdhoc(adhoc, groupRow(g)) {
+					filtered = true
+					continue
+				}
+				timestamp, err := parsePRTGTimestamp(g.DatetimeRAW, g.Datetime, loc)
 				if err != nil {
 					continue
 				}
@@ -564,8 +761,12 @@ func (d *Datasource) handlePropertyQuery(ctx context.Context, qm queryModel, pro
 			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("API request failed: %v", err))
 		}
 		for _, dev := range devices.Devices {
-			if dev.Device == qm.Device {
-				timestamp, _, err := parsePRTGDateTime(dev.Datetime)
+			if matchesObject(dev.ObjectId, dev.Device, qm.DeviceId, qm.Device) {
+				if !matchesAdhoc(adhoc, deviceRow(dev)) {
+					filtered = true
+					continue
+				}
+				timestamp, err := parsePRTGTimestamp(dev.DatetimeRAW, dev.Datetime, loc)
 				if err != nil {
 					continue
 				}
@@ -611,8 +812,12 @@ func (d *Datasource) handlePropertyQuery(ctx context.Context, qm queryModel, pro
 		}
 
 		for _, s := range sensors.Sensors {
-			if s.Sensor == qm.Sensor {
-				timestamp, _, err := parsePRTGDateTime(s.Datetime)
+			if matchesObject(s.ObjectId, s.Sensor, qm.SensorId, qm.Sensor) {
+				if !matchesAdhoc(adhoc, sensorRow(s)) {
+					filtered = true
+					continue
+				}
+				timestamp, err := parsePRTGTimestamp(s.DatetimeRAW, s.Datetime, loc)
 				if err != nil {
 					continue
 				}
@@ -663,19 +868,21 @@ func (d *Datasource) handlePropertyQuery(ctx context.Context, qm queryModel, pro
 	frameName := fmt.Sprintf("%s_%s_%s", baseFrameName, qm.Property, filterProperty)
 
 	// Build display name with optional prefixes (like in handleMetricsQuery)
-	displayName := qm.Property
-	if qm.IncludeGroupName && qm.Group != "" {
-		displayName = fmt.Sprintf("%s - %s", qm.Group, displayName)
+	displayName := ""
+	if name := legacyDisplayName(qm, qm.Property); name != "" {
+		displayName = fmt.Sprintf("%s (%s)", name, filterProperty)
 	}
-	if qm.IncludeDeviceName && qm.Device != "" {
-		displayName = fmt.Sprintf("%s - %s", qm.Device, displayName)
+
+	frame := createPropertyFrameWithDisplayName(timesRT, valuesRT, frameName, displayName, d.propertyLabels(qm, property))
+	if filtered && len(valuesRT) == 0 {
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityInfo,
+			Text:     fmt.Sprintf("The %s does not match the ad-hoc filters", property),
+		})
 	}
-	if qm.IncludeSensorName && qm.Sensor != "" {
-		displayName = fmt.Sprintf("%s - %s", qm.Sensor, displayName)
+	if len(ignored) > 0 {
+		frame.AppendNotices(ignoredAdhocNotice(ignored, fmt.Sprintf("%ss have no such column", property)))
 	}
-	displayName = fmt.Sprintf("%s (%s)", displayName, filterProperty)
-
-	frame := createPropertyFrameWithDisplayName(timesRT, valuesRT, frameName, displayName)
 
 	return backend.DataResponse{
 		Frames: []*data.Frame{frame},
@@ -683,45 +890,67 @@ func (d *Datasource) handlePropertyQuery(ctx context.Context, qm queryModel, pro
 }
 
 /* =================================== FRAME CREATOR ========================================== */
-func createPropertyFrameWithDisplayName(times []time.Time, values []interface{}, frameName, displayName string) *data.Frame {
+// createPropertyFrameWithDisplayName returns numeric property values (raw mode) as time series,
+// so they can be used in alert rules. Text values are returned as table with a string field.
+func createPropertyFrameWithDisplayName(times []time.Time, values []interface{}, frameName, displayName string, labels data.Labels) *data.Frame {
 	if len(times) == 0 || len(values) == 0 {
 		return data.NewFrame(frameName + "_empty")
 	}
 
 	timeField := data.NewField("Time", nil, times)
 	var valueField *data.Field
+	meta := &data.FrameMeta{}
 
-	switch values[0].(type) {
-	case float64, int:
-		floatVals := make([]float64, len(values))
-		for i, v := range values {
-			switch tv := v.(type) {
-			case float64:
-				floatVals[i] = tv
-			case int:
-				floatVals[i] = float64(tv)
-			}
-		}
-		valueField = data.NewField("Value", nil, floatVals)
-	case string:
+	if numbers, ok := propertyNumbers(values); ok {
+		valueField = data.NewField("Value", nil, numbers)
+		meta.Type = data.FrameTypeTimeSeriesWide
+		meta.TypeVersion = data.FrameTypeVersion{0, 1}
+	} else {
 		strVals := make([]string, len(values))
 		for i, v := range values {
-			strVals[i] = v.(string)
+			strVals[i] = propertyString(v)
 		}
 		valueField = data.NewField("Value", nil, strVals)
-	default:
-		strVals := make([]string, len(values))
-		for i, v := range values {
-			strVals[i] = fmt.Sprintf("%v", v)
+	}
+
+	valueField.Labels = labels
+	if displayName != "" {
+		valueField.Config = &data.FieldConfig{
+			DisplayNameFromDS: displayName,
 		}
-		valueField = data.NewField("Value", nil, strVals)
 	}
 
-	valueField.Config = &data.FieldConfig{
-		DisplayName: displayName,
+	return data.NewFrame(frameName, timeField, valueField).SetMeta(meta)
+}
+
+// propertyNumbers converts the values if all of them are numbers. Mixed values are not
+// converted, a frame must not mix a string and a number value.
+func propertyNumbers(values []interface{}) ([]float64, bool) {
+	numbers := make([]float64, len(values))
+	for i, v := range values {
+		switch tv := v.(type) {
+		case float64:
+			numbers[i] = tv
+		case int:
+			numbers[i] = float64(tv)
+		case int64:
+			numbers[i] = float64(tv)
+		default:
+			return nil, false
+		}
 	}
+	return numbers, true
+}
 
-	return data.NewFrame(frameName, timeField, valueField)
+func propertyString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case StringOrNumber:
+		return v.String
+	default:
+		return fmt.Sprintf("%v", v)
+	}
 }
 
 /* ###############################################  GetPropertyValue ################################################################*/
@@ -800,4 +1029,4 @@ func selectRawOrFormatted(isRaw bool, rawValue, formattedValue interface{}) inte
 		return rawValue
 	}
 	return formattedValue
-}
\ No newline at end of file
+}
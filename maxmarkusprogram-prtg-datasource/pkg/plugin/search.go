@@ -0,0 +1,324 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	// defaultMaxSeries limits the series of a sensor search without configured maximum
+	defaultMaxSeries = 50
+	// maxConcurrentSensors limits the parallel historic data requests of a sensor search
+	maxConcurrentSensors = 4
+)
+
+/* =================================== SENSOR MATCHING ========================================= */
+
+// compileNamePattern turns a filter into a case-insensitive regular expression.
+// "/.../" is used as regular expression, anything else as wildcard pattern with "*" and "?".
+// An empty filter matches everything and returns nil.
+func compileNamePattern(filter string) (*regexp.Regexp, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return nil, nil
+	}
+	if len(filter) > 1 && strings.HasPrefix(filter, "/") && strings.HasSuffix(filter, "/") {
+		return regexp.Compile("(?i)" + filter[1:len(filter)-1])
+	}
+
+	pattern := regexp.QuoteMeta(filter)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	pattern = strings.ReplaceAll(pattern, `\?`, ".")
+	return regexp.Compile("(?i)^" + pattern + "$")
+}
+
+// sensorMatcher holds the compiled filters of a sensor search
+type sensorMatcher struct {
+	group  *regexp.Regexp
+	device *regexp.Regexp
+	sensor *regexp.Regexp
+	kind   *regexp.Regexp
+	tags   []string
+}
+
+func newSensorMatcher(qm queryModel) (*sensorMatcher, error) {
+	m := &sensorMatcher{}
+	filters := []struct {
+		name   string
+		filter string
+		target **regexp.Regexp
+	}{
+		{"group", qm.GroupFilter, &m.group},
+		{"device", qm.DeviceFilter, &m.device},
+		{"sensor", qm.SensorFilter, &m.sensor},
+		{"sensor type", qm.SensorType, &m.kind},
+	}
+	for _, f := range filters {
+		re, err := compileNamePattern(f.filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s filter: %w", f.name, err)
+		}
+		*f.target = re
+	}
+
+	for _, tag := range qm.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			m.tags = append(m.tags, strings.ToLower(tag))
+		}
+	}
+
+	if m.group == nil && m.device == nil && m.sensor == nil && m.kind == nil && len(m.tags) == 0 {
+		return nil, fmt.Errorf("sensor search requires at least one tag or filter")
+	}
+	return m, nil
+}
+
+// matches reports whether a sensor passes all filters of the search
+func (m *sensorMatcher) matches(sensor PrtgSensorListItemStruct) bool {
+	if m.group != nil && !m.group.MatchString(sensor.Group) {
+		return false
+	}
+	if m.device != nil && !m.device.MatchString(sensor.Device) {
+		return false
+	}
+	if m.sensor != nil && !m.sensor.MatchString(sensor.Sensor) {
+		return false
+	}
+	if m.kind != nil && !m.kind.MatchString(sensor.TypeRAW) && !m.kind.MatchString(sensor.Type) {
+		return false
+	}
+
+	return hasAllTags(sensor.Tags, m.tags)
+}
+
+// findMatchingSensors returns the sensors matching the search of the query, sorted by name
+func (d *Datasource) findMatchingSensors(qm queryModel, matcher *sensorMatcher) ([]PrtgSensorListItemStruct, error) {
+	// PRTG filters the first tag, everything else is checked here
+	tag := ""
+	if len(matcher.tags) > 0 {
+		tag = matcher.tags[0]
+	}
+	sensors, err := d.api.FindSensors(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]PrtgSensorListItemStruct, 0)
+	for _, sensor := range sensors.Sensors {
+		if matcher.matches(sensor) {
+			matches = append(matches, sensor)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Device != b.Device {
+			return a.Device < b.Device
+		}
+		if a.Sensor != b.Sensor {
+			return a.Sensor < b.Sensor
+		}
+		return a.ObjectId < b.ObjectId
+	})
+	return matches, nil
+}
+
+/* =================================== SEARCH QUERY ============================================ */
+
+// handleSearchQuery selects sensors by tags and name filters and returns the selected channels
+// of every match, see multiSensorResponse.
+func (d *Datasource) handleSearchQuery(ctx context.Context, qm queryModel, timeRange backend.TimeRange, baseFrameName string) backend.DataResponse {
+	_, span := d.tracer.StartSpan(ctx, "handleSearchQuery")
+	defer span.End()
+
+	if len(qm.ChannelIds) == 0 && len(qm.ChannelArray) == 0 && qm.Channel == "" {
+		d.metrics.IncError("missing_channel")
+		return backend.ErrDataResponse(backend.StatusBadRequest, "channel selection required")
+	}
+
+	matcher, err := newSensorMatcher(qm)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	adhoc, err := newAdhocMatchers(qm.AdhocFilters)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	sensors, err := d.findMatchingSensors(qm, matcher)
+	if err != nil {
+		d.logger.Error("Sensor search failed", "error", err)
+		recordError(span, err, "Sensor search failed")
+		return backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("sensor search failed: %v", err))
+	}
+	sensors = filterSensors(adhoc, sensors)
+
+	return d.multiSensorResponse(qm, sensors, timeRange, baseFrameName, "search")
+}
+
+// multiSensorResponse loads the selected channels of every sensor and returns one frame per
+// series, labelled with the sensor it belongs to. The list is limited to MaxSeries, sensors
+// which could not be loaded are reported by sensorErrorNotices.
+func (d *Datasource) multiSensorResponse(qm queryModel, sensors []PrtgSensorListItemStruct, timeRange backend.TimeRange, baseFrameName, queryType string) backend.DataResponse {
+	maxSeries := qm.MaxSeries
+	if maxSeries <= 0 {
+		maxSeries = defaultMaxSeries
+	}
+	channelsPerSensor := len(qm.ChannelIds)
+	if channelsPerSensor == 0 {
+		channelsPerSensor = max(len(qm.ChannelArray), 1)
+	}
+	maxSensors := max(maxSeries/channelsPerSensor, 1)
+
+	notices := make([]data.Notice, 0)
+	if len(sensors) > maxSensors {
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text: fmt.Sprintf("%d sensors are selected, only the first %d are shown (max. %d series)",
+				len(sensors), maxSensors, maxSeries),
+		})
+		sensors = sensors[:maxSensors]
+	}
+
+	// The frames keep the sorted order of the sensors
+	results := make([]*metricResult, len(sensors))
+	errs := loadSensors(len(sensors), func(i int) (err error) {
+		results[i], err = d.loadMetricTable(searchSensorQuery(qm, sensors[i]), timeRange)
+		return err
+	})
+	errNotices, err := d.sensorErrorNotices(sensors, errs)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusInternal, err.Error())
+	}
+	notices = append(notices, errNotices...)
+
+	response := backend.DataResponse{
+		Frames: make([]*data.Frame, 0, len(sensors)),
+	}
+	for i, sensor := range sensors {
+		if errs[i] != nil {
+			continue
+		}
+		table := results[i].table
+		table.downsample(qm.Downsample, int(qm.MaxDataPoints))
+		table.fillNulls(qm.NullValueMode)
+		notices = append(notices, results[i].notices...)
+
+		sensorQuery := searchSensorQuery(qm, sensor)
+		labels := labelsFromSensor(&sensor)
+
+		// One frame per series, the sensors may report at different times
+		for _, column := range table.Columns {
+			fieldConfig := d.metricFieldConfig(sensorQuery, column, baseFrameName, queryType)
+			frame := data.NewFrame(fmt.Sprintf("%s_%d_%s", baseFrameName, sensor.ObjectId, column.Selection.Name),
+				data.NewField("Time", nil, table.Times),
+				data.NewField("Value", channelLabels(labels, column.Selection), column.Values).SetConfig(fieldConfig),
+			)
+			frame.Meta = &data.FrameMeta{
+				Type:        data.FrameTypeTimeSeriesMulti,
+				TypeVersion: data.FrameTypeVersion{0, 1},
+				Custom: map[string]interface{}{
+					"from":      timeRange.From.UnixMilli(),
+					"to":        timeRange.To.UnixMilli(),
+					"sensorId":  sensor.ObjectId,
+					"channel":   column.Selection.Name,
+					"channelId": column.Selection.ID,
+					"queryType": queryType,
+					"interval":  results[i].spacing.Milliseconds(),
+					"refId":     baseFrameName,
+				},
+			}
+			response.Frames = append(response.Frames, frame)
+		}
+	}
+
+	if len(response.Frames) == 0 {
+		response.Frames = append(response.Frames, data.NewFrame(fmt.Sprintf("%s_empty", baseFrameName)))
+	}
+	response.Frames[0].AppendNotices(notices...)
+
+	return response
+}
+
+/* =================================== SENSOR LOADING ========================================== */
+
+// loadSensors calls load for the sensors 0..n-1 with bounded concurrency and returns the
+// error of every sensor
+func loadSensors(n int, load func(i int) error) []error {
+	errs := make([]error, n)
+	semaphore := make(chan struct{}, maxConcurrentSensors)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			errs[i] = load(i)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// sensorErrorNotices reports the sensors of a multi-sensor query that could not be loaded. Sensors
+// without the selected channels are skipped with a notice, failed requests are reported as warning.
+// If no sensor could be loaded because of failed requests, the query fails.
+func (d *Datasource) sensorErrorNotices(sensors []PrtgSensorListItemStruct, errs []error) ([]data.Notice, error) {
+	missing := make([]string, 0)
+	failed := make([]string, 0)
+	var firstErr error
+	for i, err := range errs {
+		var notFound *channelNotFoundError
+		switch {
+		case err == nil:
+		case errors.As(err, &notFound):
+			d.logger.Debug("Skipping sensor without the selected channels", "sensorId", sensors[i].ObjectId, "error", err)
+			missing = append(missing, sensors[i].Sensor)
+		default:
+			d.logger.Warn("Failed to load sensor", "sensorId", sensors[i].ObjectId, "error", err)
+			failed = append(failed, fmt.Sprintf("%s (%d): %v", sensors[i].Sensor, sensors[i].ObjectId, err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if firstErr != nil && len(missing)+len(failed) == len(errs) {
+		return nil, fmt.Errorf("failed to load the %d selected sensors: %w", len(errs), firstErr)
+	}
+	notices := make([]data.Notice, 0)
+	if len(missing) > 0 {
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityInfo,
+			Text:     fmt.Sprintf("%d sensors without the selected channels were skipped: %s", len(missing), strings.Join(missing, ", ")),
+		})
+	}
+	if len(failed) > 0 {
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("%d sensors could not be loaded: %s", len(failed), strings.Join(failed, "; ")),
+		})
+	}
+	return notices, nil
+}
+
+// searchSensorQuery derives the metrics query of one sensor of a search or an id list. The series of the
+// sensors are told apart by their group, device and sensor labels.
+func searchSensorQuery(qm queryModel, sensor PrtgSensorListItemStruct) queryModel {
+	sensorQuery := qm
+	sensorQuery.SensorId = strconv.FormatInt(sensor.ObjectId, 10)
+	sensorQuery.Group = sensor.Group
+	sensorQuery.Device = sensor.Device
+	sensorQuery.Sensor = sensor.Sensor
+	return sensorQuery
+}
@@ -0,0 +1,152 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestCompileNamePattern(t *testing.T) {
+	tests := []struct {
+		filter    string
+		matches   []string
+		unmatched []string
+		wantErr   bool
+	}{
+		{filter: "web*", matches: []string{"web01", "WEB", "webserver"}, unmatched: []string{"xweb01"}},
+		{filter: "web0?", matches: []string{"web01", "Web09"}, unmatched: []string{"web1", "web010"}},
+		{filter: "a.b", matches: []string{"a.b"}, unmatched: []string{"axb"}},
+		{filter: `/^db\d+$/`, matches: []string{"db1", "DB12"}, unmatched: []string{"db", "mydb1"}},
+		{filter: "/core/", matches: []string{"Core Switch", "hardcore"}, unmatched: []string{"cor"}},
+		{filter: "/(/", wantErr: true},
+	}
+	for _, tt := range tests {
+		re, err := compileNamePattern(tt.filter)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.filter)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", tt.filter, err)
+		}
+		for _, name := range tt.matches {
+			if !re.MatchString(name) {
+				t.Errorf("%q does not match %q", tt.filter, name)
+			}
+		}
+		for _, name := range tt.unmatched {
+			if re.MatchString(name) {
+				t.Errorf("%q matches %q", tt.filter, name)
+			}
+		}
+	}
+
+	if re, err := compileNamePattern("  "); re != nil || err != nil {
+		t.Errorf("empty filter = %v, %v, want nil", re, err)
+	}
+}
+
+func TestSensorMatcher(t *testing.T) {
+	sensor := PrtgSensorListItemStruct{
+		Group: "Berlin", Device: "web01", Sensor: "Ping", Type: "Ping", TypeRAW: "ping", Tags: "pingsensor core",
+	}
+	tests := []struct {
+		name string
+		qm   queryModel
+		want bool
+	}{
+		{"all tags", queryModel{Tags: []string{"core", " PingSensor "}}, true},
+		{"missing tag", queryModel{Tags: []string{"core", "switch"}}, false},
+		{"tag prefix", queryModel{Tags: []string{"ping"}}, false},
+		{"wildcards", queryModel{GroupFilter: "Ber*", DeviceFilter: "web??"}, true},
+		{"device mismatch", queryModel{DeviceFilter: "db*"}, false},
+		{"raw sensor type", queryModel{SensorType: "/^ping$/"}, true},
+		{"sensor and tag", queryModel{SensorFilter: "Ping", Tags: []string{"switch"}}, false},
+	}
+	for _, tt := range tests {
+		m, err := newSensorMatcher(tt.qm)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := m.matches(sensor); got != tt.want {
+			t.Errorf("%s: matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := newSensorMatcher(queryModel{Tags: []string{" "}}); err == nil {
+		t.Error("a search without filters must fail")
+	}
+	if _, err := newSensorMatcher(queryModel{SensorFilter: "/[/"}); err == nil {
+		t.Error("an invalid regex must fail")
+	}
+}
+
+// multiSensorAPI has a working sensor 1001, sensor 1002 without channel 0 and sensor 1003
+// whose historic data cannot be loaded
+func multiSensorAPI() ([]PrtgSensorListItemStruct, *fakeAPI) {
+	api := alertTestAPI()
+	api.channels["1002"] = &PrtgChannelListResponse{Channels: []PrtgChannelListItemStruct{{ObjectId: 2, Name: "Traffic"}}}
+	api.historic["1002"] = api.historic["1001"]
+	api.channels["1003"] = api.channels["1001"]
+	sensors := []PrtgSensorListItemStruct{
+		{ObjectId: 1001, Device: "web01", Sensor: "Ping"},
+		{ObjectId: 1002, Device: "web01", Sensor: "Traffic"},
+		{ObjectId: 1003, Device: "web02", Sensor: "Ping"},
+	}
+	return sensors, api
+}
+
+// noticeSeverity returns the severity of the first notice containing text
+func noticeSeverity(frame *data.Frame, text string) (data.NoticeSeverity, bool) {
+	if frame.Meta == nil {
+		return 0, false
+	}
+	for _, notice := range frame.Meta.Notices {
+		if strings.Contains(notice.Text, text) {
+			return notice.Severity, true
+		}
+	}
+	return 0, false
+}
+
+func TestMultiSensorResponse(t *testing.T) {
+	sensors, api := multiSensorAPI()
+	ds := newTestDatasource(t, api, nil)
+	timeRange := backend.TimeRange{From: alertEnd.Add(-time.Hour), To: alertEnd}
+	qm := queryModel{QueryType: "search", ChannelIds: []string{"0"}}
+
+	res := ds.multiSensorResponse(qm, sensors, timeRange, "A", "search")
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if len(res.Frames) != 1 || res.Frames[0].Name != "A_1001_Ping Time" {
+		t.Fatalf("frames = %v, want the series of sensor 1001", res.Frames)
+	}
+	frame := res.Frames[0]
+	if severity, ok := noticeSeverity(frame, "without the selected channels were skipped: Traffic"); !ok || severity != data.NoticeSeverityInfo {
+		t.Errorf("notices = %v, want an info for the missing channel", frame.Meta.Notices)
+	}
+	if severity, ok := noticeSeverity(frame, "1 sensors could not be loaded: Ping (1003): failed to fetch data"); !ok || severity != data.NoticeSeverityWarning {
+		t.Errorf("notices = %v, want a warning for the failed request", frame.Meta.Notices)
+	}
+
+	// Without any loaded sensor a failed request fails the query, alert rules see an error instead of no data
+	res = ds.multiSensorResponse(qm, sensors[1:], timeRange, "A", "search")
+	if res.Error == nil {
+		t.Error("expected an error if no sensor could be loaded")
+	}
+
+	// Sensors without the channels are a selection problem, not an error
+	res = ds.multiSensorResponse(qm, sensors[1:2], timeRange, "A", "search")
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if frame := res.Frames[0]; frame.Name != "A_empty" || !hasNotice(frame, "without the selected channels") {
+		t.Errorf("frame %s, notices %v", frame.Name, frame.Meta)
+	}
+}
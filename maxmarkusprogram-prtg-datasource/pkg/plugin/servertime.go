@@ -0,0 +1,106 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// offsetGranularity is the step UTC offsets are rounded to (all real timezones are multiples of 15 minutes)
+	offsetGranularity = 15 * time.Minute
+	// maxClockSkew is the difference between the PRTG and the Grafana clock tolerated without warning
+	maxClockSkew = time.Minute
+)
+
+/* =================================== SERVER TIME ============================================= */
+
+// serverTimeInfo describes the clock of the PRTG core server as reported by status.json
+type serverTimeInfo struct {
+	// Offset is the difference between the PRTG wall clock and UTC
+	Offset time.Duration
+	// Skew is the difference between the PRTG clock and the local clock
+	Skew time.Duration
+}
+
+// detectServerTime compares the localized Clock of the status response with JsClock,
+// which is the same instant as unix timestamp, to find the effective UTC offset of PRTG.
+func detectServerTime(status *PrtgStatusListResponse, now time.Time) (*serverTimeInfo, error) {
+	if status == nil || status.JsClock <= 0 || status.Clock == "" {
+		return nil, fmt.Errorf("PRTG status contains no clock")
+	}
+
+	// jsclock is in seconds, some versions report milliseconds
+	utc := time.Unix(status.JsClock, 0).UTC()
+	if status.JsClock > 1e12 {
+		utc = time.UnixMilli(status.JsClock).UTC()
+	}
+
+	// Parsing the wall clock as UTC keeps the shown date and time unchanged
+	wallClock, _, err := parsePRTGDateTimeIn(status.Clock, time.UTC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PRTG clock: %w", err)
+	}
+
+	return &serverTimeInfo{
+		Offset: wallClock.UTC().Sub(utc).Round(offsetGranularity),
+		Skew:   utc.Sub(now).Truncate(time.Second),
+	}, nil
+}
+
+// Location returns a fixed zone with the detected offset. It does not know about DST changes,
+// so a configured timezone with the same current offset is preferred over it.
+func (i *serverTimeInfo) Location() *time.Location {
+	return time.FixedZone(formatUTCOffset(i.Offset), int(i.Offset.Seconds()))
+}
+
+// MatchesLocation reports whether loc has the detected offset at the given time
+func (i *serverTimeInfo) MatchesLocation(loc *time.Location, at time.Time) bool {
+	_, offset := at.In(loc).Zone()
+	return time.Duration(offset)*time.Second == i.Offset
+}
+
+// HasSkew reports whether the PRTG clock is off by more than maxClockSkew
+func (i *serverTimeInfo) HasSkew() bool {
+	return i.Skew > maxClockSkew || i.Skew < -maxClockSkew
+}
+
+// formatUTCOffset formats an offset like "UTC+02:00"
+func formatUTCOffset(offset time.Duration) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+	}
+	minutes := int(math.Abs(offset.Minutes()))
+	return fmt.Sprintf("UTC%s%02d:%02d", sign, minutes/60, minutes%60)
+}
+
+// checkServerTimezone compares the detected PRTG offset with the configured timezone.
+// It returns the location to use for PRTG timestamps and a list of warnings.
+// With autoTimezone the detected offset replaces a configured timezone that does not match.
+func checkServerTimezone(info *serverTimeInfo, timezone string, autoTimezone bool, now time.Time) (*time.Location, []string) {
+	warnings := make([]string, 0)
+
+	configured, err := time.LoadLocation(timezone)
+	if err != nil {
+		configured = time.UTC
+	}
+
+	if info.HasSkew() {
+		warnings = append(warnings, fmt.Sprintf("PRTG clock differs from the Grafana clock by %s", info.Skew))
+	}
+
+	if info.MatchesLocation(configured, now) {
+		return configured, warnings
+	}
+
+	if autoTimezone {
+		warnings = append(warnings, fmt.Sprintf("Configured timezone %s does not match the PRTG server offset %s, using the detected offset",
+			timezone, formatUTCOffset(info.Offset)))
+		return info.Location(), warnings
+	}
+
+	warnings = append(warnings, fmt.Sprintf("Configured timezone %s does not match the PRTG server offset %s",
+		timezone, formatUTCOffset(info.Offset)))
+	return configured, warnings
+}
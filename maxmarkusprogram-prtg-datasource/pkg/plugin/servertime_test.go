@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/1DeliDolu/PRTG/maxmarkusprogram-prtg-datasource/pkg/models"
+)
+
+func TestDatasourceLocationIsPerInstance(t *testing.T) {
+	// PRTG clock at UTC+05:00 while Europe/Berlin is configured
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	autoAPI := &fakeAPI{status: &PrtgStatusListResponse{
+		Clock:   now.Add(5 * time.Hour).Format("02.01.2006 15:04:05"),
+		JsClock: now.Unix(),
+	}}
+	auto := newTestDatasource(t, autoAPI, &models.PluginSettings{Timezone: "Europe/Berlin", AutoTimezone: true})
+	fixed := newTestDatasource(t, &fakeAPI{}, &models.PluginSettings{Timezone: "Europe/Berlin"})
+
+	if autoAPI.statusCalls != 0 {
+		t.Fatalf("status requested before the first query")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); auto.location() }()
+		go func() { defer wg.Done(); fixed.location() }()
+	}
+	wg.Wait()
+
+	if _, offset := now.In(auto.location()).Zone(); offset != 5*3600 {
+		t.Errorf("auto timezone offset = %d, want %d", offset, 5*3600)
+	}
+	if got := fixed.location().String(); got != "Europe/Berlin" {
+		t.Errorf("other instance location = %s, want Europe/Berlin", got)
+	}
+	if autoAPI.statusCalls != 1 {
+		t.Errorf("status requested %d times, want once", autoAPI.statusCalls)
+	}
+}
+
+func TestDatasourceLocationRetriesDetection(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	api := &fakeAPI{
+		status: &PrtgStatusListResponse{
+			Clock:   now.Add(5 * time.Hour).Format("02.01.2006 15:04:05"),
+			JsClock: now.Unix(),
+		},
+		statusErr: errNotFound("status"),
+	}
+	ds := newTestDatasource(t, api, &models.PluginSettings{Timezone: "Europe/Berlin", AutoTimezone: true})
+
+	// PRTG is not reachable: the configured timezone is used and not requested again right away
+	for i := 0; i < 3; i++ {
+		if got := ds.location().String(); got != "Europe/Berlin" {
+			t.Fatalf("location = %s, want the configured timezone", got)
+		}
+	}
+	if api.statusCalls != 1 {
+		t.Fatalf("status requested %d times, want once within the retry delay", api.statusCalls)
+	}
+
+	// After the retry delay the server timezone is detected
+	api.mu.Lock()
+	api.statusErr = nil
+	api.mu.Unlock()
+	ds.serverTimeMu.Lock()
+	ds.serverTimeRetry = time.Now().Add(-time.Second)
+	ds.serverTimeMu.Unlock()
+	if _, offset := now.In(ds.location()).Zone(); offset != 5*3600 {
+		t.Errorf("offset after retry = %d, want %d", offset, 5*3600)
+	}
+	ds.location()
+	if api.statusCalls != 2 {
+		t.Errorf("status requested %d times, want 2", api.statusCalls)
+	}
+}
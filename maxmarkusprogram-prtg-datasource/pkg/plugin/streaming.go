@@ -48,7 +48,7 @@ func (d *Datasource) SubscribeStream(ctx context.Context, req *backend.Subscribe
 	}
 
 	// Required fields validation
-	if query.SensorId == "" || (len(query.ChannelArray) == 0 && query.Channel == "") {
+	if query.SensorId == "" || (len(query.ChannelIds) == 0 && len(query.ChannelArray) == 0 && query.Channel == "") {
 		d.logger.Error("Missing required fields", "sensorId", query.SensorId)
 		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusPermissionDenied}, nil
 	}
@@ -150,6 +150,9 @@ func (d *Datasource) RunStream(ctx context.Context, req *backend.RunStreamReques
 // Helper functions for better organization and testability
 
 func getChannels(query queryModel) []string {
+	if len(query.ChannelIds) > 0 {
+		return query.ChannelIds
+	}
 	if len(query.ChannelArray) > 0 {
 		return query.ChannelArray
 	}
@@ -176,11 +179,14 @@ func getBoundedInterval(requestedInterval int64) time.Duration {
 
 func generateStreamID(query queryModel, channels []string) string {
 	channelKey := strings.Join(channels, "_")
-	return fmt.Sprintf("%v_%s_%s_%s",
+	// Alignment and timezone change the timestamps, buffers of different settings must not be mixed
+	return fmt.Sprintf("%v_%s_%s_%s_%s_%s",
 		query.PanelID,
 		query.RefID,
 		query.SensorId,
-		channelKey)
+		channelKey,
+		timestampAlignment(query.TimestampAlignment),
+		query.Timezone)
 }
 
 func getTimeRange(query queryModel) (time.Time, time.Time) {
@@ -259,7 +265,7 @@ func createStream(query queryModel, channels []string, streamID string,
 			isActive:  true,
 			buffer: &dataBuffer{
 				times:  make([]time.Time, 0, InitialBufferCapacity),
-				values: make([]float64, 0, InitialBufferCapacity),
+				values: make([]*float64, 0, InitialBufferCapacity),
 				size:   bufferSize,
 			},
 		}
@@ -458,8 +464,11 @@ func (d *Datasource) processResponseFrames(
 			continue
 		}
 
-		// Get channel state
+		// Get channel state, streams of id based queries are keyed by channel id
 		channelState, exists := stream.channelStates[channelName]
+		if !exists {
+			channelState, exists = stream.channelStates[extractChannelID(frame)]
+		}
 		if !exists {
 			continue
 		}
@@ -470,6 +479,10 @@ func (d *Datasource) processResponseFrames(
 			continue
 		}
 
+		// Keep unit, thresholds and value mappings of the metrics frame
+		channelState.config = frame.Fields[1].Config
+		channelState.labels = frame.Fields[1].Labels
+
 		// Update buffer
 		updateChannelBuffer(stream, channelState, times, values)
 
@@ -509,7 +522,18 @@ func extractChannelName(frame *data.Frame) string {
 	return ""
 }
 
-func extractFrameData(frame *data.Frame) ([]time.Time, []float64) {
+func extractChannelID(frame *data.Frame) string {
+	if frame.Meta != nil && frame.Meta.Custom != nil {
+		if metaMap, ok := frame.Meta.Custom.(map[string]interface{}); ok {
+			if id, exists := metaMap["channelId"]; exists {
+				return fmt.Sprint(id)
+			}
+		}
+	}
+	return ""
+}
+
+func extractFrameData(frame *data.Frame) ([]time.Time, []*float64) {
 	if len(frame.Fields) < 2 || frame.Fields[0].Len() == 0 {
 		return nil, nil
 	}
@@ -518,25 +542,31 @@ func extractFrameData(frame *data.Frame) ([]time.Time, []float64) {
 	valueField := frame.Fields[1]
 
 	times := make([]time.Time, timeField.Len())
-	values := make([]float64, valueField.Len())
+	values := make([]*float64, valueField.Len())
 
 	// Extract with type checking
 	for i := 0; i < timeField.Len(); i++ {
 		if t, ok := timeField.At(i).(time.Time); ok {
 			times[i] = t
 		}
-		if v, ok := valueField.At(i).(float64); ok {
+		switch v := valueField.At(i).(type) {
+		case *float64:
 			values[i] = v
+		case float64:
+			values[i] = &v
 		}
 	}
 
 	return times, values
 }
 
-func updateChannelBuffer(stream *activeStream, state *channelState, times []time.Time, values []float64) {
-	// Update last value
-	if len(values) > 0 {
-		state.lastValue = values[len(values)-1]
+func updateChannelBuffer(stream *activeStream, state *channelState, times []time.Time, values []*float64) {
+	// Update last value, skipping trailing nulls
+	for i := len(values) - 1; i >= 0; i-- {
+		if values[i] != nil {
+			state.lastValue = *values[i]
+			break
+		}
 	}
 
 	// Update buffer based on mode
@@ -558,7 +588,7 @@ func updateChannelBuffer(stream *activeStream, state *channelState, times []time
 }
 
 // Optimized buffer data append
-func appendBufferData(buffer *dataBuffer, newTimes []time.Time, newValues []float64, maxSize int64) {
+func appendBufferData(buffer *dataBuffer, newTimes []time.Time, newValues []*float64, maxSize int64) {
 	curLen := len(buffer.times)
 	newLen := curLen + len(newTimes)
 
@@ -587,13 +617,19 @@ func createStreamingFrame(stream *activeStream, channelName string, state *chann
 	// Build display name
 	displayName := buildDisplayName(stream, channelName)
 
+	// Reuse the field config of the metrics frame so units and mappings survive streaming
+	fieldConfig := &data.FieldConfig{}
+	if state.config != nil {
+		copied := *state.config
+		fieldConfig = &copied
+	}
+	fieldConfig.DisplayNameFromDS = displayName
+
 	// Create frame with buffer data
 	frameName := fmt.Sprintf("stream_%s_%s", stream.sensorId, channelName)
 	frame := data.NewFrame(frameName,
 		data.NewField("Time", nil, state.buffer.times),
-		data.NewField("Value", nil, state.buffer.values).SetConfig(&data.FieldConfig{
-			DisplayName: displayName,
-		}),
+		data.NewField("Value", state.labels, state.buffer.values).SetConfig(fieldConfig),
 	)
 
 	// Set optimized metadata for live indicators
@@ -612,7 +648,8 @@ func createStreamingFrame(stream *activeStream, channelName string, state *chann
 
 	// Frame metadata with streaming indicators
 	frame.Meta = &data.FrameMeta{
-		Type: data.FrameTypeTimeSeriesMulti,
+		Type:        data.FrameTypeTimeSeriesMulti,
+		TypeVersion: data.FrameTypeVersion{0, 1},
 		Custom: map[string]interface{}{
 			"from":           from.UnixMilli(),
 			"to":             to.UnixMilli(),
@@ -632,8 +669,11 @@ func createStreamingFrame(stream *activeStream, channelName string, state *chann
 	return frame
 }
 
-// Helper for building consistent display names
+// Helper for building consistent display names, empty without any Include* option
 func buildDisplayName(stream *activeStream, channelName string) string {
+	if !stream.includeGroupName && !stream.includeDeviceName && !stream.includeSensorName {
+		return ""
+	}
 	displayName := channelName
 
 	if stream.includeGroupName && stream.group != "" {
@@ -647,4 +687,4 @@ func buildDisplayName(stream *activeStream, channelName string) string {
 	}
 
 	return displayName
-}
\ No newline at end of file
+}
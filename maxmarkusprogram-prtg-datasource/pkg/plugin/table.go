@@ -0,0 +1,303 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// tableContent describes a PRTG content type of a table query
+type tableContent struct {
+	content        string // content parameter of table.json
+	defaultColumns string // columns if the query selects none
+	adhocKeys      string // columns ad-hoc filters apply to
+}
+
+var tableContents = map[string]tableContent{
+	// Probes are the top level groups, PRTG lists them as probenode
+	"probes":   {"probenode", "objid,name,status,message", "probe,status,tags,priority"},
+	"groups":   {"groups", "objid,group,probe,status,message", "probe,group,status,tags,priority"},
+	"devices":  {"devices", "objid,device,host,group,probe,status,message", "probe,group,device,status,tags,priority"},
+	"sensors":  {"sensors", "objid,sensor,device,group,probe,status,lastvalue,message", "probe,group,device,sensor,status,tags,type,priority"},
+	"channels": {"channels", "objid,name,lastvalue", ""},
+}
+
+// Columns returned as time (raw value is an OLE date) and as text (formatted value)
+var (
+	tableTimeColumns = map[string]bool{"datetime": true, "lastcheck": true, "lastup": true, "lastdown": true}
+	tableTextColumns = map[string]bool{
+		"probe": true, "group": true, "device": true, "sensor": true, "name": true, "host": true,
+		"status": true, "message": true, "tags": true, "type": true, "priority": true, "active": true,
+		"parentid": true, "comments": true,
+	}
+)
+
+// Kinds of table columns
+const (
+	columnText = iota
+	columnNumber
+	columnTime
+)
+
+// tableColumn is one output column of a table query
+type tableColumn struct {
+	name   string // as selected, "<column>_raw" returns the raw value
+	source string // key of the value in the table.json row
+	kind   int
+}
+
+/* =================================== TABLE QUERY ============================================= */
+
+// handleTableQuery lists the objects of a content type with the selected columns. Numbers and
+// times are typed fields; a table without time columns is returned as numeric-long frame, so
+// alert rules can evaluate e.g. the last value per sensor.
+func (d *Datasource) handleTableQuery(ctx context.Context, qm queryModel, frameName string) backend.DataResponse {
+	_, span := d.tracer.StartSpan(ctx, "handleTableQuery")
+	defer span.End()
+
+	spec, ok := tableContents[qm.TableContent]
+	if !ok {
+		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("unknown table content '%s'", qm.TableContent))
+	}
+	loc, err := d.queryLocation(qm.Timezone)
+	if err != nil {
+		d.metrics.IncError("invalid_timezone")
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	selected := qm.TableColumns
+	if len(selected) == 0 {
+		selected = strings.Split(spec.defaultColumns, ",")
+	}
+	for _, column := range selected {
+		if !isValidColumnName(column) {
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("invalid column '%s'", column))
+		}
+	}
+
+	// Filters of the query apply to any column, ad-hoc filters only to the object columns
+	filters, err := newFilterMatchers(qm.TableFilters, isValidColumnName)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	adhoc, err := newAdhocMatchers(qm.AdhocFilters)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	adhoc, ignored := splitAdhocFilters(adhoc, spec.adhocKeys)
+	filters = append(filters, adhoc...)
+
+	// Filtered columns are loaded as well, even if they are not shown
+	requested := []string{"objid"}
+	for _, column := range selected {
+		requested = appendColumn(requested, strings.TrimSuffix(column, "_raw"))
+	}
+	for _, m := range filters {
+		requested = appendColumn(requested, m.Key)
+	}
+	requestedColumns := strings.Join(requested, ",")
+
+	params := map[string]string{"columns": requestedColumns}
+	if qm.TableContent == "channels" {
+		if qm.SensorId == "" {
+			return backend.ErrDataResponse(backend.StatusBadRequest, "channel table requires a sensor")
+		}
+		params["id"] = qm.SensorId
+	}
+	remaining, _ := applyAdhocParams(filters, requestedColumns, params)
+
+	allRows, err := d.api.GetTable(spec.content, params)
+	if err != nil {
+		d.logger.Error("Table query failed", "content", spec.content, "error", err)
+		recordError(span, err, "Table query failed")
+		return backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("API request failed: %v", err))
+	}
+	rows := make([]map[string]interface{}, 0, len(allRows))
+	for _, row := range allRows {
+		if matchesAdhoc(remaining, row) {
+			rows = append(rows, row)
+		}
+	}
+
+	columns := make([]tableColumn, len(selected))
+	for i, name := range selected {
+		columns[i] = newTableColumn(name, rows)
+	}
+
+	if qm.TableSort != "" {
+		sortColumn := -1
+		for i, column := range columns {
+			if column.name == qm.TableSort {
+				sortColumn = i
+			}
+		}
+		if sortColumn < 0 {
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("sort column '%s' is not selected", qm.TableSort))
+		}
+		sortRows(rows, columns[sortColumn], qm.TableSortDesc)
+	}
+	if qm.Limit > 0 && int64(len(rows)) > qm.Limit {
+		rows = rows[:qm.Limit]
+	}
+
+	frame := data.NewFrame(frameName)
+	hasTime, hasNumber := false, false
+	for _, column := range columns {
+		frame.Fields = append(frame.Fields, column.field(rows, loc))
+		hasTime = hasTime || column.kind == columnTime
+		hasNumber = hasNumber || column.kind == columnNumber
+	}
+
+	meta := &data.FrameMeta{
+		Type: data.FrameTypeTable,
+		Custom: map[string]interface{}{
+			"content": qm.TableContent,
+			"rows":    len(rows),
+		},
+	}
+	if hasNumber && !hasTime {
+		meta.Type = data.FrameTypeNumericLong
+		meta.TypeVersion = data.FrameTypeVersion{0, 1}
+	}
+	frame.Meta = meta
+	if len(ignored) > 0 {
+		frame.AppendNotices(ignoredAdhocNotice(ignored, fmt.Sprintf("%s have no such column", qm.TableContent)))
+	}
+
+	return backend.DataResponse{Frames: []*data.Frame{frame}}
+}
+
+/* =================================== COLUMNS ================================================= */
+
+// isValidColumnName accepts PRTG column names: letters, digits and underscores
+func isValidColumnName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func appendColumn(columns []string, column string) []string {
+	for _, c := range columns {
+		if c == column {
+			return columns
+		}
+	}
+	return append(columns, column)
+}
+
+// newTableColumn determines the type of a column. Text columns keep the formatted value,
+// other columns become numbers if all raw values are numeric.
+func newTableColumn(name string, rows []map[string]interface{}) tableColumn {
+	base := strings.TrimSuffix(name, "_raw")
+	column := tableColumn{name: name, source: name, kind: columnText}
+
+	if tableTimeColumns[base] {
+		column.source = base + "_raw"
+		column.kind = columnTime
+		return column
+	}
+	if name == base && tableTextColumns[base] {
+		return column
+	}
+
+	// Prefer the raw value, the formatted one contains units and separators
+	source := name
+	if name == base && len(rows) > 0 {
+		if _, ok := rows[0][base+"_raw"]; ok {
+			source = base + "_raw"
+		}
+	}
+	for _, row := range rows {
+		if rowString(row, source) == "" {
+			continue
+		}
+		if _, ok := rowFloat(row, source); !ok {
+			return column
+		}
+	}
+	column.source = source
+	column.kind = columnNumber
+	return column
+}
+
+// rowFloat returns a column of a table.json row as number
+func rowFloat(row map[string]interface{}, column string) (float64, bool) {
+	switch v := row[column].(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// field builds the typed frame field of the column
+func (c tableColumn) field(rows []map[string]interface{}, loc *time.Location) *data.Field {
+	switch c.kind {
+	case columnNumber:
+		values := make([]*float64, len(rows))
+		for i, row := range rows {
+			if f, ok := rowFloat(row, c.source); ok {
+				values[i] = &f
+			}
+		}
+		return data.NewField(c.name, nil, values)
+	case columnTime:
+		values := make([]*time.Time, len(rows))
+		for i, row := range rows {
+			values[i] = rowTime(row, c.source, loc)
+		}
+		return data.NewField(c.name, nil, values)
+	default:
+		values := make([]string, len(rows))
+		for i, row := range rows {
+			values[i] = rowString(row, c.source)
+		}
+		if c.source == "message" {
+			for i := range values {
+				values[i] = cleanMessageHTML(values[i])
+			}
+		}
+		return data.NewField(c.name, nil, values)
+	}
+}
+
+// sortRows sorts by a column, numbers and times numerically, text case-insensitively.
+// Empty cells are last in both directions.
+func sortRows(rows []map[string]interface{}, column tableColumn, desc bool) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if column.kind == columnText {
+			sa, sb := strings.ToLower(rowString(a, column.source)), strings.ToLower(rowString(b, column.source))
+			if (sa == "") != (sb == "") {
+				return sb == ""
+			}
+			if desc {
+				return sa > sb
+			}
+			return sa < sb
+		}
+		fa, okA := rowFloat(a, column.source)
+		fb, okB := rowFloat(b, column.source)
+		if okA != okB {
+			return okA
+		}
+		if desc {
+			return fa > fb
+		}
+		return fa < fb
+	})
+}
@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func sortedColumn(rows []map[string]interface{}, column string) string {
+	values := make([]string, len(rows))
+	for i, row := range rows {
+		values[i] = rowString(row, column)
+	}
+	return strings.Join(values, ",")
+}
+
+func TestSortRows(t *testing.T) {
+	rows := func() []map[string]interface{} {
+		return []map[string]interface{}{
+			{"device": "web02", "lastvalue_raw": 5.0},
+			{"device": ""},
+			{"device": "DB01", "lastvalue_raw": 12.0},
+			{"device": "web01", "lastvalue_raw": ""},
+			{"device": "app01", "lastvalue_raw": 1.0},
+		}
+	}
+	tests := []struct {
+		column tableColumn
+		desc   bool
+		want   string
+		source string
+	}{
+		{tableColumn{source: "device", kind: columnText}, false, "app01,DB01,web01,web02,", "device"},
+		{tableColumn{source: "device", kind: columnText}, true, "web02,web01,DB01,app01,", "device"},
+		{tableColumn{source: "lastvalue_raw", kind: columnNumber}, false, "app01,web02,DB01,,web01", "device"},
+		{tableColumn{source: "lastvalue_raw", kind: columnNumber}, true, "DB01,web02,app01,,web01", "device"},
+	}
+	for _, tt := range tests {
+		sorted := rows()
+		sortRows(sorted, tt.column, tt.desc)
+		if got := sortedColumn(sorted, tt.source); got != tt.want {
+			t.Errorf("sort by %s (desc %v) = %s, want %s; empty cells are last", tt.column.source, tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestTableQuery(t *testing.T) {
+	api := &fakeAPI{tables: func(content string, params map[string]string) []map[string]interface{} {
+		return []map[string]interface{}{
+			{"objid": 1001.0, "sensor": "Ping", "device": "web01", "lastvalue": "12 ms", "lastvalue_raw": 12.0, "status": "Up", "status_raw": 3.0},
+			{"objid": 1002.0, "sensor": "CPU", "device": "web01", "lastvalue": "85 %", "lastvalue_raw": 85.0, "status": "Warning", "status_raw": 4.0},
+			{"objid": 1003.0, "sensor": "Disk", "device": "db01", "lastvalue": "40 %", "lastvalue_raw": 40.0, "status": "Up", "status_raw": 3.0},
+			{"objid": 1004.0, "sensor": "HTTP", "device": "web02", "lastvalue": "", "lastvalue_raw": "", "status": "Paused", "status_raw": 7.0},
+		}
+	}}
+	ds := newTestDatasource(t, api, nil)
+	qm := queryModel{
+		QueryType:     "table",
+		TableContent:  "sensors",
+		TableColumns:  []string{"sensor", "lastvalue", "status_raw"},
+		TableFilters:  []adhocFilter{{Key: "device", Operator: "=~", Value: "^web"}},
+		TableSort:     "lastvalue",
+		TableSortDesc: true,
+		Limit:         2,
+	}
+
+	res := ds.handleTableQuery(context.Background(), qm, "A")
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+	if frame.Rows() != 2 {
+		t.Fatalf("got %d rows, want 2", frame.Rows())
+	}
+	if got := frame.Fields[0].At(0).(string) + "," + frame.Fields[0].At(1).(string); got != "CPU,Ping" {
+		t.Errorf("sensors = %s, want the web sensors by last value", got)
+	}
+	if value, ok := frame.Fields[1].At(0).(*float64); !ok || value == nil || *value != 85 {
+		t.Errorf("last value = %v, want the raw number", frame.Fields[1].At(0))
+	}
+	if frame.Meta.Type != data.FrameTypeNumericLong {
+		t.Errorf("frame type = %s, want numeric long for alerting", frame.Meta.Type)
+	}
+	if got := api.tableRequests[0]["columns"]; got != "objid,sensor,lastvalue,status,device" {
+		t.Errorf("columns = %s, want the selected and filtered columns", got)
+	}
+
+	qm.TableSort = "priority"
+	if res := ds.handleTableQuery(context.Background(), qm, "A"); res.Error == nil {
+		t.Error("sorting by a column which is not selected must fail")
+	}
+}
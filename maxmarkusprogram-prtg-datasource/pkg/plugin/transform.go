@@ -0,0 +1,254 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Types of per-channel transforms
+const (
+	TransformRate          = "rate"          // change per second, e.g. for counters
+	TransformDelta         = "delta"         // change to the previous value
+	TransformMultiply      = "multiply"      // multiply by Value
+	TransformDivide        = "divide"        // divide by Value
+	TransformConvertUnit   = "convertUnit"   // convert between units of the same kind, see unitFactors
+	TransformMovingAverage = "movingAverage" // average of the last Window values
+	TransformEWMA          = "ewma"          // exponentially weighted moving average with Alpha
+)
+
+// channelTransform is one step of the transform list of a metrics query
+type channelTransform struct {
+	Type string `json:"type"`
+	// Channel id or name the transform applies to, empty for all channels
+	Channel string `json:"channel"`
+	// Factor of multiply/divide
+	Value float64 `json:"value"`
+	// Number of values of movingAverage
+	Window int `json:"window"`
+	// Smoothing factor of ewma, between 0 and 1
+	Alpha float64 `json:"alpha"`
+	// Source and target unit of convertUnit (Grafana unit ids), From defaults to the channel unit
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Drop negative results of rate/delta, e.g. caused by counter resets
+	NonNegative bool `json:"nonNegative"`
+	// Unit of the result (Grafana unit id), keeps the current unit if empty
+	Unit string `json:"unit"`
+}
+
+/* =================================== UNIT CONVERSION ========================================= */
+
+// unitFactor describes a Grafana unit as multiple of the base unit of its kind
+type unitFactor struct {
+	kind   string
+	factor float64
+}
+
+// unitFactors lists the units convertUnit can convert between
+var unitFactors = map[string]unitFactor{
+	// Data, base: byte
+	"bits":      {"data", 1.0 / 8},
+	"decbytes":  {"data", 1},
+	"deckbytes": {"data", 1e3},
+	"decmbytes": {"data", 1e6},
+	"decgbytes": {"data", 1e9},
+	"dectbytes": {"data", 1e12},
+	"bytes":     {"data", 1},
+	"kbytes":    {"data", 1 << 10},
+	"mbytes":    {"data", 1 << 20},
+	"gbytes":    {"data", 1 << 30},
+	"tbytes":    {"data", 1 << 40},
+	// Data rate, base: bit/s
+	"bps":   {"rate", 1},
+	"Kbits": {"rate", 1e3},
+	"Mbits": {"rate", 1e6},
+	"Gbits": {"rate", 1e9},
+	"Bps":   {"rate", 8},
+	"KBs":   {"rate", 8e3},
+	"MBs":   {"rate", 8e6},
+	"GBs":   {"rate", 8e9},
+	// Time, base: second
+	"ns": {"time", 1e-9},
+	"µs": {"time", 1e-6},
+	"ms": {"time", 1e-3},
+	"s":  {"time", 1},
+	"m":  {"time", 60},
+	"h":  {"time", 3600},
+	"d":  {"time", 86400},
+	// Power, base: watt
+	"watt":  {"power", 1},
+	"kwatt": {"power", 1e3},
+	// Frequency, base: hertz
+	"hertz":  {"frequency", 1},
+	"khertz": {"frequency", 1e3},
+	"mhertz": {"frequency", 1e6},
+	"ghertz": {"frequency", 1e9},
+}
+
+// unitConverter returns a function converting values from one unit to another
+func unitConverter(from, to string) (func(float64) float64, error) {
+	if from == to {
+		return func(v float64) float64 { return v }, nil
+	}
+
+	// Temperatures are not proportional
+	switch {
+	case from == "celsius" && to == "fahrenheit":
+		return func(v float64) float64 { return v*9/5 + 32 }, nil
+	case from == "fahrenheit" && to == "celsius":
+		return func(v float64) float64 { return (v - 32) * 5 / 9 }, nil
+	}
+
+	source, okFrom := unitFactors[from]
+	target, okTo := unitFactors[to]
+	if !okFrom || !okTo || source.kind != target.kind {
+		return nil, fmt.Errorf("cannot convert unit '%s' to '%s'", from, to)
+	}
+	factor := source.factor / target.factor
+	return func(v float64) float64 { return v * factor }, nil
+}
+
+/* =================================== TRANSFORMS ============================================== */
+
+// matches reports whether the transform applies to the selected channel
+func (tr channelTransform) matches(sel channelSelection) bool {
+	return tr.Channel == "" || tr.Channel == sel.ID || strings.EqualFold(tr.Channel, sel.Name)
+}
+
+// applyTransforms runs the transforms in order on the matching columns of the table.
+// A transformed column no longer has the scale of the PRTG channel, see metricColumn.Transformed.
+func (t *metricTable) applyTransforms(transforms []channelTransform) error {
+	for i, tr := range transforms {
+		matched := false
+		for _, column := range t.Columns {
+			if !tr.matches(column.Selection) {
+				continue
+			}
+			matched = true
+			if err := t.applyTransform(column, tr); err != nil {
+				return fmt.Errorf("transform %d (%s): %w", i+1, tr.Type, err)
+			}
+			column.Transformed = true
+			if tr.Unit != "" {
+				column.Unit = tr.Unit
+			}
+		}
+		if !matched {
+			return fmt.Errorf("transform %d (%s): unknown channel '%s'", i+1, tr.Type, tr.Channel)
+		}
+	}
+	return nil
+}
+
+func (t *metricTable) applyTransform(column *metricColumn, tr channelTransform) error {
+	switch tr.Type {
+	case TransformRate, TransformDelta:
+		column.Values = t.differences(column.Values, tr.Type == TransformRate, tr.NonNegative)
+	case TransformMultiply:
+		column.Values = mapValues(column.Values, func(v float64) float64 { return v * tr.Value })
+	case TransformDivide:
+		if tr.Value == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		column.Values = mapValues(column.Values, func(v float64) float64 { return v / tr.Value })
+	case TransformConvertUnit:
+		from := tr.From
+		if from == "" {
+			from = column.unit()
+		}
+		convert, err := unitConverter(from, tr.To)
+		if err != nil {
+			return err
+		}
+		column.Values = mapValues(column.Values, convert)
+		column.Unit = tr.To
+	case TransformMovingAverage:
+		if tr.Window < 1 {
+			return fmt.Errorf("window must be at least 1")
+		}
+		column.Values = movingAverage(column.Values, tr.Window)
+	case TransformEWMA:
+		if tr.Alpha <= 0 || tr.Alpha > 1 {
+			return fmt.Errorf("alpha must be between 0 and 1")
+		}
+		column.Values = ewma(column.Values, tr.Alpha)
+	default:
+		return fmt.Errorf("unknown transform type")
+	}
+	return nil
+}
+
+// differences returns the change to the previous value, per second if perSecond is set.
+// The first value and values following a null have no predecessor and become null.
+func (t *metricTable) differences(values []*float64, perSecond, nonNegative bool) []*float64 {
+	result := make([]*float64, len(values))
+	for i := 1; i < len(values); i++ {
+		if values[i] == nil || values[i-1] == nil {
+			continue
+		}
+		diff := *values[i] - *values[i-1]
+		if perSecond {
+			seconds := t.Times[i].Sub(t.Times[i-1]).Seconds()
+			if seconds <= 0 {
+				continue
+			}
+			diff /= seconds
+		}
+		if nonNegative && diff < 0 {
+			continue
+		}
+		result[i] = &diff
+	}
+	return result
+}
+
+func mapValues(values []*float64, fn func(float64) float64) []*float64 {
+	result := make([]*float64, len(values))
+	for i, value := range values {
+		if value != nil {
+			v := fn(*value)
+			result[i] = &v
+		}
+	}
+	return result
+}
+
+// movingAverage averages the non-null values among the last window rows
+func movingAverage(values []*float64, window int) []*float64 {
+	result := make([]*float64, len(values))
+	sum := 0.0
+	count := 0
+	for i, value := range values {
+		if value != nil {
+			sum += *value
+			count++
+		}
+		if i >= window && values[i-window] != nil {
+			sum -= *values[i-window]
+			count--
+		}
+		if value != nil && count > 0 {
+			avg := sum / float64(count)
+			result[i] = &avg
+		}
+	}
+	return result
+}
+
+// ewma smoothes the values exponentially, nulls are skipped and stay null
+func ewma(values []*float64, alpha float64) []*float64 {
+	result := make([]*float64, len(values))
+	var current *float64
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		next := *value
+		if current != nil {
+			next = alpha*(*value) + (1-alpha)*(*current)
+		}
+		current = &next
+		result[i] = current
+	}
+	return result
+}
@@ -0,0 +1,235 @@
+package plugin
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// transformTable returns a table with one channel, the rows are 10s apart unless times are given
+func transformTable(values []*float64, times ...time.Time) *metricTable {
+	if len(times) == 0 {
+		start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := range values {
+			times = append(times, start.Add(time.Duration(i)*10*time.Second))
+		}
+	}
+	return &metricTable{
+		Times: times,
+		Columns: []*metricColumn{{
+			Selection: channelSelection{ID: "2", Name: "Traffic", Info: &PrtgChannelListItemStruct{Unit: "ms"}},
+			Values:    values,
+		}},
+	}
+}
+
+// equalValues compares nullable values with a tolerance for rounding
+func equalValues(got, want []*float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if (got[i] == nil) != (want[i] == nil) {
+			return false
+		}
+		if got[i] != nil && math.Abs(*got[i]-*want[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func formatValues(values []*float64) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		if v != nil {
+			result[i] = *v
+		}
+	}
+	return result
+}
+
+func TestApplyTransforms(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		values    []*float64
+		times     []time.Time
+		transform channelTransform
+		want      []*float64
+		unit      string
+	}{
+		{
+			name:      "rate",
+			values:    []*float64{ptr(100), ptr(200), ptr(500)},
+			transform: channelTransform{Type: TransformRate},
+			want:      []*float64{nil, ptr(10), ptr(30)},
+		},
+		{
+			name:      "rate with nulls",
+			values:    []*float64{ptr(100), nil, ptr(300), ptr(400)},
+			transform: channelTransform{Type: TransformRate},
+			want:      []*float64{nil, nil, nil, ptr(10)},
+		},
+		{
+			name:      "rate across a counter reset",
+			values:    []*float64{ptr(100), ptr(200), ptr(50), ptr(150)},
+			transform: channelTransform{Type: TransformRate},
+			want:      []*float64{nil, ptr(10), ptr(-15), ptr(10)},
+		},
+		{
+			name:      "non-negative rate drops counter resets",
+			values:    []*float64{ptr(100), ptr(200), ptr(50), ptr(150)},
+			transform: channelTransform{Type: TransformRate, NonNegative: true},
+			want:      []*float64{nil, ptr(10), nil, ptr(10)},
+		},
+		{
+			name:      "rate without time delta",
+			values:    []*float64{ptr(100), ptr(200), ptr(300)},
+			times:     []time.Time{start, start, start.Add(10 * time.Second)},
+			transform: channelTransform{Type: TransformRate},
+			want:      []*float64{nil, nil, ptr(10)},
+		},
+		{
+			name:      "delta",
+			values:    []*float64{ptr(1), ptr(4), nil, ptr(2), ptr(1)},
+			transform: channelTransform{Type: TransformDelta},
+			want:      []*float64{nil, ptr(3), nil, nil, ptr(-1)},
+		},
+		{
+			name:      "multiply",
+			values:    []*float64{ptr(2), nil, ptr(-3)},
+			transform: channelTransform{Type: TransformMultiply, Value: 8, Unit: "bps"},
+			want:      []*float64{ptr(16), nil, ptr(-24)},
+			unit:      "bps",
+		},
+		{
+			name:      "divide",
+			values:    []*float64{ptr(10), nil},
+			transform: channelTransform{Type: TransformDivide, Value: 4},
+			want:      []*float64{ptr(2.5), nil},
+		},
+		{
+			name:      "convert the channel unit",
+			values:    []*float64{ptr(1500), nil},
+			transform: channelTransform{Type: TransformConvertUnit, To: "s"},
+			want:      []*float64{ptr(1.5), nil},
+			unit:      "s",
+		},
+		{
+			name:      "convert bytes to bits",
+			values:    []*float64{ptr(1024)},
+			transform: channelTransform{Type: TransformConvertUnit, From: "kbytes", To: "bits"},
+			want:      []*float64{ptr(1024 * 1024 * 8)},
+			unit:      "bits",
+		},
+		{
+			name:      "convert temperature",
+			values:    []*float64{ptr(100), ptr(-40)},
+			transform: channelTransform{Type: TransformConvertUnit, From: "celsius", To: "fahrenheit"},
+			want:      []*float64{ptr(212), ptr(-40)},
+			unit:      "fahrenheit",
+		},
+		{
+			name:      "moving average",
+			values:    []*float64{ptr(1), ptr(2), ptr(3), ptr(4)},
+			transform: channelTransform{Type: TransformMovingAverage, Window: 2},
+			want:      []*float64{ptr(1), ptr(1.5), ptr(2.5), ptr(3.5)},
+		},
+		{
+			name:      "moving average skips nulls",
+			values:    []*float64{ptr(2), nil, ptr(4), ptr(6)},
+			transform: channelTransform{Type: TransformMovingAverage, Window: 3},
+			want:      []*float64{ptr(2), nil, ptr(3), ptr(5)},
+		},
+		{
+			name:      "ewma",
+			values:    []*float64{ptr(10), nil, ptr(20), ptr(20)},
+			transform: channelTransform{Type: TransformEWMA, Alpha: 0.5},
+			want:      []*float64{ptr(10), nil, ptr(15), ptr(17.5)},
+		},
+		{
+			name:      "channel by name",
+			values:    []*float64{ptr(1)},
+			transform: channelTransform{Type: TransformMultiply, Value: 2, Channel: "traffic"},
+			want:      []*float64{ptr(2)},
+		},
+	}
+	for _, tt := range tests {
+		table := transformTable(tt.values, tt.times...)
+		if err := table.applyTransforms([]channelTransform{tt.transform}); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		column := table.Columns[0]
+		if !equalValues(column.Values, tt.want) {
+			t.Errorf("%s: values = %v, want %v", tt.name, formatValues(column.Values), formatValues(tt.want))
+		}
+		if !column.Transformed {
+			t.Errorf("%s: column not marked as transformed", tt.name)
+		}
+		if tt.unit != "" && column.unit() != tt.unit {
+			t.Errorf("%s: unit = %q, want %q", tt.name, column.unit(), tt.unit)
+		}
+	}
+}
+
+func TestApplyTransformsErrors(t *testing.T) {
+	tests := []struct {
+		transform channelTransform
+		err       string
+	}{
+		{channelTransform{Type: TransformDivide}, "division by zero"},
+		{channelTransform{Type: TransformConvertUnit, From: "bytes", To: "ms"}, "cannot convert unit 'bytes' to 'ms'"},
+		{channelTransform{Type: TransformConvertUnit, From: "furlong", To: "s"}, "cannot convert unit 'furlong' to 's'"},
+		{channelTransform{Type: TransformMovingAverage}, "window must be at least 1"},
+		{channelTransform{Type: TransformEWMA, Alpha: 1.5}, "alpha must be between 0 and 1"},
+		{channelTransform{Type: "median"}, "unknown transform type"},
+		{channelTransform{Type: TransformRate, Channel: "99"}, "unknown channel '99'"},
+	}
+	for _, tt := range tests {
+		table := transformTable([]*float64{ptr(1), ptr(2)})
+		err := table.applyTransforms([]channelTransform{tt.transform})
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want %q", tt.transform.Type, err, tt.err)
+		}
+	}
+}
+
+func TestUnitFactors(t *testing.T) {
+	tests := []struct {
+		from, to string
+		value    float64
+		want     float64
+	}{
+		{"decgbytes", "decmbytes", 1, 1000},
+		{"gbytes", "mbytes", 1, 1024},
+		{"MBs", "Mbits", 1, 8},
+		{"bps", "Kbits", 1500, 1.5},
+		{"h", "m", 2, 120},
+		{"µs", "ns", 1, 1000},
+		{"kwatt", "watt", 1.5, 1500},
+		{"ghertz", "mhertz", 2, 2000},
+		{"fahrenheit", "celsius", 212, 100},
+		{"s", "s", 5, 5},
+	}
+	for _, tt := range tests {
+		convert, err := unitConverter(tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("%s to %s: %v", tt.from, tt.to, err)
+		}
+		if got := convert(tt.value); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%v %s = %v %s, want %v", tt.value, tt.from, got, tt.to, tt.want)
+		}
+	}
+
+	// Every unit converts to the other units of its kind only
+	for from, source := range unitFactors {
+		for to, target := range unitFactors {
+			_, err := unitConverter(from, to)
+			if (err == nil) != (source.kind == target.kind) {
+				t.Errorf("%s (%s) to %s (%s): error %v", from, source.kind, to, target.kind, err)
+			}
+		}
+	}
+}
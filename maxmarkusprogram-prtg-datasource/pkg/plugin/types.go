@@ -2,11 +2,15 @@ package plugin
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/1DeliDolu/PRTG/maxmarkusprogram-prtg-datasource/pkg/models"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 )
 
 /* =================================== GROUP LIST RESPONSE ======================================== */
@@ -162,6 +166,12 @@ type PrtgSensorListItemStruct struct {
 	UpsensRAW      int            `json:"upsens_raw"`
 	Warnsens       string         `json:"warnsens"`
 	WarnsensRAW    int            `json:"warnsens_raw"`
+	Probe          string         `json:"probe"`
+	ProbeRAW       string         `json:"probe_raw"`
+	Interval       string         `json:"interval"`
+	IntervalRAW    int64          `json:"interval_raw"`
+	Type           string         `json:"type"`
+	TypeRAW        string         `json:"type_raw"`
 }
 
 /* =================================== STATUS LIST RESPONSE ===================================== */
@@ -202,24 +212,114 @@ type PrtgStatusListResponse struct {
 }
 
 /* =================================== CHANNEL LIST RESPONSE ==================================== */
-type PrtgChannelsListResponse struct {
-	PrtgVersion string                   `json:"prtg-version"`
-	TreeSize    int64                    `json:"treesize"`
-	Values      []PrtgChannelValueStruct `json:"values"`
+type PrtgChannelListResponse struct {
+	PrtgVersion string                      `json:"prtg-version"`
+	TreeSize    int64                       `json:"treesize"`
+	Channels    []PrtgChannelListItemStruct `json:"channels"`
+}
+
+type PrtgChannelListItemStruct struct {
+	ObjectId        int64         `json:"objid"`
+	ObjectIdRAW     int64         `json:"objid_raw"`
+	Name            string        `json:"name"`
+	NameRAW         string        `json:"name_raw"`
+	Lastvalue       string        `json:"lastvalue"`
+	LastvalueRAW    OptionalFloat `json:"lastvalue_raw"`
+	Unit            string        `json:"unit"`
+	LimitMaxError   OptionalFloat `json:"limitmaxerror"`
+	LimitMaxWarning OptionalFloat `json:"limitmaxwarning"`
+	LimitMinError   OptionalFloat `json:"limitminerror"`
+	LimitMinWarning OptionalFloat `json:"limitminwarning"`
+	ValueLookup     string        `json:"valuelookup"`
+}
+
+// OptionalFloat holds a numeric PRTG column which is returned as an empty
+// string when the value is not set (e.g. a channel without limits)
+type OptionalFloat struct {
+	Value float64
+	Valid bool
+	// Raw is a localized value like "1.234,5", it is parsed by Float with the separator of the datasource
+	Raw string
+}
+
+func (o *OptionalFloat) UnmarshalJSON(data []byte) error {
+	o.Value, o.Valid, o.Raw = 0, false, ""
+	if string(data) == "null" {
+		return nil
+	}
+
+	var num float64
+	if err := json.Unmarshal(data, &num); err == nil {
+		o.Value, o.Valid = num, true
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		// null or any other type means "not set"
+		return nil
+	}
+	o.Raw = strings.TrimSpace(str)
+	return nil
+}
+
+func (o OptionalFloat) MarshalJSON() ([]byte, error) {
+	switch {
+	case o.Valid:
+		return json.Marshal(o.Value)
+	case o.Raw != "":
+		return json.Marshal(o.Raw)
+	}
+	return []byte("null"), nil
 }
 
-type PrtgChannelValueStruct map[string]interface{}
+// Float returns the value, a localized value is parsed with the decimal separator of the PRTG server
+func (o OptionalFloat) Float(decimal string) (float64, bool) {
+	if o.Valid {
+		return o.Value, true
+	}
+	if o.Raw == "" {
+		return 0, false
+	}
+	return parseLocaleFloat(o.Raw, decimal)
+}
+
+/* =================================== VALUE LOOKUP RESPONSE ==================================== */
+type PrtgValueLookup struct {
+	XMLName        xml.Name                `xml:"ValueLookup" json:"-"`
+	ID             string                  `xml:"id,attr" json:"id"`
+	DesiredValue   string                  `xml:"desiredValue,attr" json:"desiredValue"`
+	UndefinedState string                  `xml:"undefinedState,attr" json:"undefinedState"`
+	SingleInts     []PrtgValueLookupSingle `xml:"Lookups>SingleInt" json:"singleInts"`
+	Ranges         []PrtgValueLookupRange  `xml:"Lookups>Range" json:"ranges"`
+}
+
+type PrtgValueLookupSingle struct {
+	State string  `xml:"state,attr" json:"state"`
+	Value float64 `xml:"value,attr" json:"value"`
+	Text  string  `xml:",chardata" json:"text"`
+}
+
+type PrtgValueLookupRange struct {
+	State string  `xml:"state,attr" json:"state"`
+	From  float64 `xml:"from,attr" json:"from"`
+	To    float64 `xml:"to,attr" json:"to"`
+	Text  string  `xml:",chardata" json:"text"`
+}
 
 /* =================================== CHANNEL VALUE RESPONSE =================================== */
 type PrtgHistoricalDataResponse struct {
 	PrtgVersion string       `json:"prtg-version"`
 	TreeSize    int64        `json:"treesize"`
 	HistData    []PrtgValues `json:"histdata"`
+	// Number of chunk requests which hit the row limit, set by the plugin
+	TruncatedChunks int `json:"truncatedChunks,omitempty"`
 }
 
 type PrtgValues struct {
-	Datetime string                 `json:"datetime"`
-	Value    map[string]interface{} `json:"-"`
+	Datetime    string                 `json:"datetime"`
+	DatetimeRAW float64                `json:"datetime_raw"`
+	Value       map[string]interface{} `json:"-"`
 }
 
 func (p *PrtgValues) UnmarshalJSON(data []byte) error {
@@ -230,21 +330,44 @@ func (p *PrtgValues) UnmarshalJSON(data []byte) error {
 	if dt, ok := raw["datetime"].(string); ok {
 		p.Datetime = dt
 	}
+	if dt, ok := raw["datetime_raw"].(float64); ok {
+		p.DatetimeRAW = dt
+	}
 	delete(raw, "datetime")
+	delete(raw, "datetime_raw")
 	p.Value = raw
 	return nil
 }
 
+// MarshalJSON writes the channel values next to the datetime columns again,
+// so cached responses unmarshal to the same values
+func (p PrtgValues) MarshalJSON() ([]byte, error) {
+	raw := make(map[string]interface{}, len(p.Value)+2)
+	for k, v := range p.Value {
+		raw[k] = v
+	}
+	raw["datetime"] = p.Datetime
+	raw["datetime_raw"] = p.DatetimeRAW
+	return json.Marshal(raw)
+}
+
 /* =================================== DATASOURCE INTERFACE ==================================== */
 type PRTGAPI interface {
 	GetGroups() (*PrtgGroupListResponse, error)
 	GetStatusList() (*PrtgStatusListResponse, error)
 	GetDevices(groupId string) (*PrtgDevicesListResponse, error)
 	GetSensors(deviceId string) (*PrtgSensorsListResponse, error)
-	GetChannels(sensorId string) (*PrtgChannelValueStruct, error)
-	GetHistoricalData(sensorId string, from time.Time, to time.Time) (*PrtgHistoricalDataResponse, error)
+	GetSensorDetails(sensorId string) (*PrtgSensorListItemStruct, error)
+	FindSensors(tag string) (*PrtgSensorsListResponse, error)
+	GetChannels(sensorId string) (*PrtgChannelListResponse, error)
+	GetTable(content string, params map[string]string) ([]map[string]interface{}, error)
+	// Like GetChannels and GetTable with a short cache, for current values
+	GetLiveChannels(sensorId string) (*PrtgChannelListResponse, error)
+	GetLiveTable(content string, params map[string]string) ([]map[string]interface{}, error)
+	GetLookup(lookupId string) (*PrtgValueLookup, error)
+	GetHistoricalData(sensorId string, from time.Time, to time.Time, loc *time.Location) (*PrtgHistoricalDataResponse, error)
 	ExecuteManualMethod(method string, objectId string) (*PrtgManualMethodResponse, error)
-	GetAnnotationData(query *AnnotationQuery) (*AnnotationResponse, error)
+	GetAnnotationData(query *AnnotationQuery, loc *time.Location) (*AnnotationResponse, error)
 	GetCacheTime() time.Duration
 }
 
@@ -288,6 +411,62 @@ type queryModel struct {
 	StreamInterval    int64    `json:"streamInterval"`
 	UpdateMode        string   `json:"updateMode"` // Add this field for stream update mode
 	RefID             string   `json:"refId"`
+
+	// PRTG channel ids; preferred over the captions in Channel/ChannelArray
+	ChannelIds []string `json:"channelIds"`
+	// Skip applying unit, min/max and thresholds from the PRTG channel settings
+	DisableChannelConfig bool `json:"disableChannelConfig"`
+	// How missing values are treated: "null" (default), "previous" or "zero"
+	NullValueMode string `json:"nullValueMode"`
+	// Which point of an averaging interval is used as timestamp: "start", "midpoint" or "end" (default)
+	TimestampAlignment string `json:"timestampAlignment"`
+	// Add the start and end of the averaging interval as extra time fields
+	IncludeIntervalBounds bool `json:"includeIntervalBounds"`
+	// IANA timezone of the PRTG timestamps, overrides the datasource timezone for this query
+	Timezone string `json:"timezone"`
+	// Backend downsampling to MaxDataPoints: "min", "max", "mean" or "lttb", empty disables it
+	Downsample string `json:"downsample"`
+	// Maximum number of points of the panel, taken from the data query
+	MaxDataPoints int64 `json:"maxDataPoints"`
+	// Per-channel transforms, applied in order after parsing the historic data
+	Transforms []channelTransform `json:"transforms"`
+	// Arithmetic expression over ExpressionSeries, e.g. "in + out"; replaces the channel series
+	Expression string `json:"expression"`
+	// Named channels of this or other sensors used in Expression
+	ExpressionSeries []expressionSeries `json:"expressionSeries"`
+	// Display name and Grafana unit of the expression result
+	ExpressionName string `json:"expressionName"`
+	ExpressionUnit string `json:"expressionUnit"`
+	// Sensor search: wildcard ("*", "?") or /regex/ patterns on the object names and the sensor type.
+	// Tags are matched all together.
+	GroupFilter  string `json:"groupFilter"`
+	DeviceFilter string `json:"deviceFilter"`
+	SensorFilter string `json:"sensorFilter"`
+	SensorType   string `json:"sensorType"`
+	// Maximum number of series of a sensor search
+	MaxSeries int `json:"maxSeries"`
+	// Object list of a template variable query
+	Variable variableQuery `json:"variable"`
+	// Several objects, e.g. from multi-value variables; one series per object.
+	// SensorId, DeviceId and GroupId may also hold a comma separated list.
+	SensorIds []string `json:"sensorIds"`
+	DeviceIds []string `json:"deviceIds"`
+	GroupIds  []string `json:"groupIds"`
+	// Grafana ad-hoc filters on the object columns (status, tags, ...) of sensor lists
+	AdhocFilters []adhocFilter `json:"adhocFilters"`
+	// Table query: content type (probes, groups, devices, sensors, channels), PRTG columns,
+	// filters on any column and sort column; the channel table lists the channels of SensorId
+	TableContent  string        `json:"tableContent"`
+	TableColumns  []string      `json:"tableColumns"`
+	TableFilters  []adhocFilter `json:"tableFilters"`
+	TableSort     string        `json:"tableSort"`
+	TableSortDesc bool          `json:"tableSortDesc"`
+	// Alarms query: states to list (down, partial, acknowledged, warning, unusual; default all)
+	// and the minimum sensor priority (1-5)
+	AlarmStates      []string `json:"alarmStates"`
+	AlarmMinPriority int64    `json:"alarmMinPriority"`
+	// Logs query: message types (status of the log entry, e.g. "Down", "Up", "Paused") to include
+	LogTypes []string `json:"logTypes"`
 }
 
 /* =================================== DATASOURCE ============================================== */
@@ -306,10 +485,17 @@ type ApiInterface interface {
 	GetGroups() (*PrtgGroupListResponse, error)
 	GetDevices(group string) (*PrtgDevicesListResponse, error)
 	GetSensors(device string) (*PrtgSensorsListResponse, error)
-	GetChannels(sensorId string) (*PrtgChannelValueStruct, error)
-	GetHistoricalData(sensorID string, startDate, endDate time.Time) (*PrtgHistoricalDataResponse, error)
+	GetSensorDetails(sensorId string) (*PrtgSensorListItemStruct, error)
+	FindSensors(tag string) (*PrtgSensorsListResponse, error)
+	GetChannels(sensorId string) (*PrtgChannelListResponse, error)
+	GetTable(content string, params map[string]string) ([]map[string]interface{}, error)
+	// Like GetChannels and GetTable with a short cache, for current values
+	GetLiveChannels(sensorId string) (*PrtgChannelListResponse, error)
+	GetLiveTable(content string, params map[string]string) ([]map[string]interface{}, error)
+	GetLookup(lookupId string) (*PrtgValueLookup, error)
+	GetHistoricalData(sensorID string, startDate, endDate time.Time, loc *time.Location) (*PrtgHistoricalDataResponse, error)
 	ExecuteManualMethod(method string, objectId string) (*PrtgManualMethodResponse, error)
-	GetAnnotationData(query *AnnotationQuery) (*AnnotationResponse, error)
+	GetAnnotationData(query *AnnotationQuery, loc *time.Location) (*AnnotationResponse, error)
 }
 
 type Api struct {
@@ -400,6 +586,7 @@ func (k QueryCacheKey) String() string {
 
 type QueryCacheEntry struct {
 	Response   backend.DataResponse
+	Responses  backend.Responses // All responses of a request, used by QueryData
 	ValidUntil time.Time
 	Updating   bool
 }
@@ -422,13 +609,15 @@ type streamStatus struct {
 type channelState struct {
 	lastValue float64
 	isActive  bool
-	buffer    *dataBuffer // Reference to dataBuffer type
+	buffer    *dataBuffer       // Reference to dataBuffer type
+	config    *data.FieldConfig // Field config (unit, thresholds, mappings) of the source frame
+	labels    data.Labels       // Series labels of the source frame
 }
 
 // Use this dataBuffer definition and remove the one in streaming.go
 type dataBuffer struct {
 	times  []time.Time
-	values []float64
+	values []*float64
 	size   int64
 }
 
@@ -474,4 +663,15 @@ type Datasource struct {
 	cacheMutex    sync.RWMutex
 	cacheTime     time.Duration
 	streamManager *streamManager
-}
\ No newline at end of file
+	// Decimal separator of formatted PRTG values, DecimalAuto guesses it per value
+	decimalSeparator string
+	// Settings of this instance and the location its PRTG timestamps are interpreted in.
+	// The location may be replaced by the detected server offset, see location().
+	settings   *models.PluginSettings
+	loc        *time.Location
+	locationMu sync.RWMutex
+	// Timezone detection state of autoTimezone, a failed detection is retried after serverTimeRetryDelay
+	serverTimeMu       sync.Mutex
+	serverTimeDetected bool
+	serverTimeRetry    time.Time
+}
@@ -0,0 +1,249 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Object kinds of a variable query
+const (
+	VariableGroups   = "groups"
+	VariableDevices  = "devices"
+	VariableSensors  = "sensors"
+	VariableChannels = "channels"
+)
+
+// variableQuery lists PRTG objects as text/value pairs for dashboard variables.
+// Chained variables pass the value of the parent variable as ParentId or as name filter.
+type variableQuery struct {
+	Kind string `json:"kind"`
+	// Object id the list is restricted to, e.g. the group of devices; required for channels
+	ParentId string `json:"parentId"`
+	// Exact group and device names, e.g. from another variable
+	Group  string `json:"group"`
+	Device string `json:"device"`
+	// All tags must be set on the object
+	Tags []string `json:"tags"`
+	// Status name (up, warning, down, paused, unusual, ...) or PRTG status code
+	Status string `json:"status"`
+	// Wildcard ("*", "?") or /regex/ pattern on the sensor type
+	SensorType string `json:"sensorType"`
+	// Regular expression the object name must match
+	Regex string `json:"regex"`
+	// "id" (default) returns object ids as values, "name" the object names
+	ValueField string `json:"valueField"`
+}
+
+// variableOption is one entry of a variable, as expected by Grafana
+type variableOption struct {
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
+// variableTable describes the table.json request of an object kind
+type variableTable struct {
+	columns    string
+	textColumn string
+}
+
+var variableTables = map[string]variableTable{
+	VariableGroups:   {"objid,group,probe,status,tags", "group"},
+	VariableDevices:  {"objid,device,group,probe,status,tags", "device"},
+	VariableSensors:  {"objid,sensor,device,group,probe,status,tags,type", "sensor"},
+	VariableChannels: {"objid,name", "name"},
+}
+
+// statusFilterCodes maps status names to the PRTG status codes of filter_status. PRTG pauses
+// objects by user, dependency, schedule, license or until a time, "paused" covers all of them.
+var statusFilterCodes = map[string][]string{
+	"unknown":      {"1"},
+	"up":           {"3"},
+	"warning":      {"4"},
+	"down":         {"5"},
+	"paused":       {"7", "8", "9", "11", "12"},
+	"unusual":      {"10"},
+	"acknowledged": {"13"},
+	"partial":      {"14"},
+}
+
+// statusCodes returns the PRTG status codes of a status name or a numeric status code
+func statusCodes(status string) ([]string, bool) {
+	if codes, ok := statusFilterCodes[strings.ToLower(status)]; ok {
+		return codes, true
+	}
+	if _, err := strconv.Atoi(status); err == nil {
+		return []string{status}, true
+	}
+	return nil, false
+}
+
+// hasStatus reports whether the status_raw column of a table.json row is one of the codes
+func hasStatus(row map[string]interface{}, codes []string) bool {
+	status := rowString(row, "status_raw")
+	for _, code := range codes {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+/* =================================== VARIABLE OPTIONS ======================================== */
+
+// variableOptions loads the objects of the query and returns them sorted by name
+func (d *Datasource) variableOptions(vq variableQuery) ([]variableOption, error) {
+	table, ok := variableTables[vq.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable kind '%s'", vq.Kind)
+	}
+	if vq.Kind == VariableChannels && vq.ParentId == "" {
+		return nil, fmt.Errorf("channels require the sensor id as parentId")
+	}
+
+	params := map[string]string{"columns": table.columns}
+	if vq.ParentId != "" {
+		params["id"] = vq.ParentId
+	}
+	if vq.Group != "" && vq.Kind != VariableGroups && vq.Kind != VariableChannels {
+		params["filter_group"] = vq.Group
+	}
+	if vq.Device != "" && vq.Kind == VariableSensors {
+		params["filter_device"] = vq.Device
+	}
+	// PRTG accepts one filter_status per request, several codes are checked on the rows
+	var statuses []string
+	if vq.Status != "" {
+		codes, ok := statusCodes(vq.Status)
+		if !ok {
+			return nil, fmt.Errorf("unknown status '%s'", vq.Status)
+		}
+		if len(codes) == 1 {
+			params["filter_status"] = codes[0]
+		} else {
+			statuses = codes
+		}
+	}
+
+	// PRTG filters the first tag, everything else is checked on the rows
+	tags := make([]string, 0, len(vq.Tags))
+	for _, tag := range vq.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, strings.ToLower(tag))
+		}
+	}
+	if len(tags) > 0 {
+		params["filter_tags"] = fmt.Sprintf("@tag(%s)", tags[0])
+	}
+
+	var nameRegex *regexp.Regexp
+	if vq.Regex != "" {
+		re, err := regexp.Compile(vq.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		nameRegex = re
+	}
+	typePattern, err := compileNamePattern(vq.SensorType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sensor type filter: %w", err)
+	}
+
+	rows, err := d.api.GetTable(vq.Kind, params)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{})
+	options := make([]variableOption, 0, len(rows))
+	for _, row := range rows {
+		text := rowString(row, table.textColumn)
+		if text == "" || (nameRegex != nil && !nameRegex.MatchString(text)) {
+			continue
+		}
+		if typePattern != nil && !typePattern.MatchString(rowString(row, "type")) && !typePattern.MatchString(rowString(row, "type_raw")) {
+			continue
+		}
+		if !hasAllTags(rowString(row, "tags"), tags) || (statuses != nil && !hasStatus(row, statuses)) {
+			continue
+		}
+
+		value := rowString(row, "objid")
+		if vq.ValueField == "name" {
+			value = text
+		}
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		options = append(options, variableOption{Text: text, Value: value})
+	}
+
+	sort.SliceStable(options, func(i, j int) bool { return options[i].Text < options[j].Text })
+	return options, nil
+}
+
+// rowString returns a column of a table.json row as string
+func rowString(row map[string]interface{}, column string) string {
+	switch v := row[column].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// hasAllTags reports whether the space or comma separated tag list contains all tags (lower case)
+func hasAllTags(tagList string, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	present := make(map[string]struct{})
+	for _, tag := range strings.FieldsFunc(strings.ToLower(tagList), func(r rune) bool { return r == ' ' || r == ',' }) {
+		present[tag] = struct{}{}
+	}
+	for _, tag := range tags {
+		if _, ok := present[tag]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+/* =================================== VARIABLE QUERY ========================================== */
+
+// handleVariableQuery returns the options as frame with the fields "text" and "value"
+func (d *Datasource) handleVariableQuery(ctx context.Context, vq variableQuery, frameName string) backend.DataResponse {
+	_, span := d.tracer.StartSpan(ctx, "handleVariableQuery")
+	defer span.End()
+
+	options, err := d.variableOptions(vq)
+	if err != nil {
+		d.metrics.IncError("variable_query_failed")
+		recordError(span, err, "Variable query failed")
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	texts := make([]string, len(options))
+	values := make([]string, len(options))
+	for i, option := range options {
+		texts[i] = option.Text
+		values[i] = option.Value
+	}
+
+	frame := data.NewFrame(frameName,
+		data.NewField("text", nil, texts),
+		data.NewField("value", nil, values),
+	)
+	return backend.DataResponse{Frames: []*data.Frame{frame}}
+}
@@ -0,0 +1,168 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// variableTestAPI serves a small PRTG tree: two groups, a device in each and their sensors.
+// Like PRTG it restricts the rows by id (parent), filter_status and filter_device.
+func variableTestAPI() *fakeAPI {
+	tree := map[string][]map[string]interface{}{
+		"groups": {
+			{"objid": 10.0, "group": "Linux", "status_raw": 3.0},
+			{"objid": 20.0, "group": "Windows", "status_raw": 3.0},
+		},
+		"devices": {
+			{"objid": 100.0, "device": "web01", "group": "Linux", "parent": "10", "status_raw": 3.0},
+			{"objid": 200.0, "device": "dc01", "group": "Windows", "parent": "20", "status_raw": 3.0},
+		},
+		"sensors": {
+			{"objid": 1001.0, "sensor": "Ping", "device": "web01", "parent": "100", "status_raw": 3.0, "type": "Ping"},
+			{"objid": 1002.0, "sensor": "CPU", "device": "web01", "parent": "100", "status_raw": 7.0, "type": "SSH CPU"},
+			{"objid": 1003.0, "sensor": "Disk", "device": "web01", "parent": "100", "status_raw": 12.0, "type": "SSH Disk"},
+			{"objid": 2001.0, "sensor": "Ping", "device": "dc01", "parent": "200", "status_raw": 5.0, "type": "Ping"},
+		},
+		"channels": {
+			{"objid": 0.0, "name": "Ping Time", "parent": "1001"},
+			{"objid": 1.0, "name": "Packet Loss", "parent": "1001"},
+		},
+	}
+	return &fakeAPI{tables: func(content string, params map[string]string) []map[string]interface{} {
+		rows := make([]map[string]interface{}, 0)
+		for _, row := range tree[content] {
+			if id := params["id"]; id != "" && row["parent"] != id {
+				continue
+			}
+			if status := params["filter_status"]; status != "" && rowString(row, "status_raw") != status {
+				continue
+			}
+			if device := params["filter_device"]; device != "" && row["device"] != device {
+				continue
+			}
+			rows = append(rows, row)
+		}
+		return rows
+	}}
+}
+
+func optionValues(options []variableOption) []string {
+	values := make([]string, len(options))
+	for i, option := range options {
+		values[i] = option.Value
+	}
+	return values
+}
+
+func TestVariableChaining(t *testing.T) {
+	ds := newTestDatasource(t, variableTestAPI(), nil)
+
+	// Every level passes the selected value of its parent variable as parentId
+	steps := []struct {
+		kind   string
+		parent string
+		want   []string
+	}{
+		{VariableGroups, "", []string{"10", "20"}},
+		{VariableDevices, "10", []string{"100"}},
+		{VariableSensors, "100", []string{"1002", "1003", "1001"}},
+		{VariableChannels, "1001", []string{"1", "0"}},
+	}
+	for _, step := range steps {
+		options, err := ds.variableOptions(variableQuery{Kind: step.kind, ParentId: step.parent})
+		if err != nil {
+			t.Fatalf("%s of %q: %v", step.kind, step.parent, err)
+		}
+		if got := optionValues(options); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s of %q = %v, want %v", step.kind, step.parent, got, step.want)
+		}
+	}
+
+	// Name based chaining, e.g. a device variable with valueField name
+	options, err := ds.variableOptions(variableQuery{Kind: VariableSensors, Device: "dc01", ValueField: "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := optionValues(options); !reflect.DeepEqual(got, []string{"Ping"}) {
+		t.Errorf("sensors of device dc01 = %v, want [Ping]", got)
+	}
+
+	if _, err := ds.variableOptions(variableQuery{Kind: VariableChannels}); err == nil {
+		t.Error("channels without parentId did not fail")
+	}
+}
+
+func TestVariableStatusFilter(t *testing.T) {
+	api := variableTestAPI()
+	ds := newTestDatasource(t, api, nil)
+
+	// Paused covers all paused codes and is checked on the rows
+	options, err := ds.variableOptions(variableQuery{Kind: VariableSensors, Status: "paused"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := optionValues(options); !reflect.DeepEqual(got, []string{"1002", "1003"}) {
+		t.Errorf("paused sensors = %v, want [1002 1003]", got)
+	}
+	if status, ok := api.tableRequests[len(api.tableRequests)-1]["filter_status"]; ok {
+		t.Errorf("paused sent filter_status=%s, want no PRTG filter", status)
+	}
+
+	options, err = ds.variableOptions(variableQuery{Kind: VariableSensors, Status: "down"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := optionValues(options); !reflect.DeepEqual(got, []string{"2001"}) {
+		t.Errorf("down sensors = %v, want [2001]", got)
+	}
+
+	if _, err := ds.variableOptions(variableQuery{Kind: VariableSensors, Status: "sleeping"}); err == nil {
+		t.Error("unknown status did not fail")
+	}
+}
+
+func TestVariablesResource(t *testing.T) {
+	ds := newTestDatasource(t, variableTestAPI(), nil)
+
+	call := func(url string) *backend.CallResourceResponse {
+		t.Helper()
+		var resp *backend.CallResourceResponse
+		err := ds.CallResource(context.Background(), &backend.CallResourceRequest{
+			Path:   "variables",
+			Method: http.MethodGet,
+			URL:    url,
+		}, backend.CallResourceResponseSenderFunc(func(r *backend.CallResourceResponse) error {
+			resp = r
+			return nil
+		}))
+		if err != nil {
+			t.Fatalf("CallResource %s: %v", url, err)
+		}
+		if resp == nil {
+			t.Fatalf("CallResource %s sent no response", url)
+		}
+		return resp
+	}
+
+	resp := call("variables?kind=sensors&parentId=100&type=SSH*")
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status = %d: %s", resp.Status, resp.Body)
+	}
+	var options []variableOption
+	if err := json.Unmarshal(resp.Body, &options); err != nil {
+		t.Fatal(err)
+	}
+	want := []variableOption{{Text: "CPU", Value: "1002"}, {Text: "Disk", Value: "1003"}}
+	if !reflect.DeepEqual(options, want) {
+		t.Errorf("options = %+v, want %+v", options, want)
+	}
+
+	if resp := call("variables?kind=probes"); resp.Status != http.StatusBadRequest {
+		t.Errorf("unknown kind returned status %d, want 400", resp.Status)
+	}
+}
@@ -1,12 +1,18 @@
 /** @jsx React.createElement */
 import React, { ChangeEvent } from 'react';
-import { Combobox, InlineField, Input, SecretInput } from '@grafana/ui';
+import { Combobox, InlineField, InlineSwitch, Input, RadioButtonGroup, SecretInput } from '@grafana/ui';
 import { DataSourcePluginOptionsEditorProps } from '@grafana/data';
 import { MyDataSourceOptions, MySecureJsonData } from '../types';
 import { timezoneOptions } from '../timezone'
 
 interface Props extends DataSourcePluginOptionsEditorProps<MyDataSourceOptions, MySecureJsonData> { }
 
+const decimalSeparatorOptions: Array<{ label: string; value: '' | ',' | '.' }> = [
+  { label: 'Auto', value: '' },
+  { label: 'Comma (1.234,5)', value: ',' },
+  { label: 'Point (1,234.5)', value: '.' },
+];
+
 export function ConfigEditor(props: Props) {
   const { onOptionsChange, options } = props;
   const { jsonData, secureJsonFields, secureJsonData } = options;
@@ -81,6 +87,26 @@ export function ConfigEditor(props: Props) {
     });
   };
 
+  const onAutoTimezoneChange = (event: React.FormEvent<HTMLInputElement>) => {
+    onOptionsChange({
+      ...options,
+      jsonData: {
+        ...jsonData,
+        autoTimezone: event.currentTarget.checked,
+      },
+    });
+  };
+
+  const onDecimalSeparatorChange = (value: '' | ',' | '.') => {
+    onOptionsChange({
+      ...options,
+      jsonData: {
+        ...jsonData,
+        decimalSeparator: value,
+      },
+    });
+  };
+
   return (
     <div>
       <InlineField label="Path" labelWidth={14} interactive tooltip="Json field returned to frontend">
@@ -123,6 +149,30 @@ export function ConfigEditor(props: Props) {
           width={60}
         />
       </InlineField>
+      <InlineField
+        label="Auto Timezone"
+        labelWidth={14}
+        interactive
+        tooltip="Use the UTC offset of the PRTG server clock if it does not match the selected timezone"
+      >
+        <InlineSwitch
+          id="config-editor-auto-timezone"
+          value={jsonData.autoTimezone || false}
+          onChange={onAutoTimezoneChange}
+        />
+      </InlineField>
+      <InlineField
+        label="Decimal Sep."
+        labelWidth={14}
+        interactive
+        tooltip="Decimal separator of formatted PRTG values, depends on the language of the PRTG server. Auto guesses it per value."
+      >
+        <RadioButtonGroup
+          options={decimalSeparatorOptions}
+          value={jsonData.decimalSeparator || ''}
+          onChange={onDecimalSeparatorChange}
+        />
+      </InlineField>
     </div>
   );
 }
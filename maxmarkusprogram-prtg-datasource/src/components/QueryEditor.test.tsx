@@ -175,14 +175,11 @@ const mockSensorsResponse = {
 };
 
 const mockChannelsResponse = {
-    values: [
-        {
-            datetime: '2024-01-01',
-            'Freier Auslagerungsspeicher': 100,
-            channel1: 100,
-            channel2: 200,
-            channel3: 300,
-        },
+    channels: [
+        { objid: 0, name: 'Freier Auslagerungsspeicher', lastvalue: '100 %', unit: '%' },
+        { objid: 1, name: 'channel1', lastvalue: '100', unit: '' },
+        { objid: 2, name: 'channel2', lastvalue: '200', unit: '' },
+        { objid: 3, name: 'channel3', lastvalue: '300', unit: '' },
     ],
 };
 
@@ -7,16 +7,54 @@ import {
   InlineSwitch,
   Input,
   AsyncMultiSelect,
+  MultiSelect,
 } from '@grafana/ui';
 import { QueryEditorProps, SelectableValue } from '@grafana/data'
 import type { ComboboxOption } from '@grafana/ui';
 import { DataSource } from '../datasource'
 import {
-  MyDataSourceOptions, MyQuery, queryTypeOptions, QueryType, propertyList, filterPropertyList, manualApiMethods
+  MyDataSourceOptions, MyQuery, queryTypeOptions, QueryType, propertyList, filterPropertyList, manualApiMethods,
+  AdhocFilter
 } from '../types'
 
 type Props = QueryEditorProps<DataSource, MyQuery, MyDataSourceOptions>
 
+const tableContentOptions: Array<ComboboxOption<string>> = [
+  { label: 'Probes', value: 'probes' },
+  { label: 'Groups', value: 'groups' },
+  { label: 'Devices', value: 'devices' },
+  { label: 'Sensors', value: 'sensors' },
+  { label: 'Channels', value: 'channels', description: 'Channels of the selected sensor' },
+];
+
+const alarmStateOptions: Array<SelectableValue<string>> = [
+  { label: 'Down', value: 'down' },
+  { label: 'Partial', value: 'partial', description: 'Partially down' },
+  { label: 'Acknowledged', value: 'acknowledged' },
+  { label: 'Warning', value: 'warning' },
+  { label: 'Unusual', value: 'unusual' },
+];
+
+// Filters are edited as text, e.g. "status = Down; type =~ ping"
+const filterPattern = /^\s*(\w+)\s*(=~|!~|!=|=|<|>)\s*(.*?)\s*$/;
+
+function formatFilters(filters?: AdhocFilter[]): string {
+  return (filters || []).map(({ key, operator, value }) => `${key} ${operator} ${value}`).join('; ');
+}
+
+function parseFilters(text: string): AdhocFilter[] {
+  return text.split(';').flatMap((part) => {
+    const match = filterPattern.exec(part);
+    return match ? [{ key: match[1], operator: match[2], value: match[3] }] : [];
+  });
+}
+
+const splitList = (text: string) =>
+  text
+    .split(',')
+    .map((item) => item.trim())
+    .filter((item) => item !== '');
+
 export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props) {
   const prevQueryRef = useRef<MyQuery | null>(null);
   const runQueryIfChanged = useCallback(() => {
@@ -33,6 +71,11 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
   const isRawMode = query.queryType === QueryType.Raw
   const isTextMode = query.queryType === QueryType.Text
   const isManualMode = query.queryType === QueryType.Manual
+  const isSearchMode = query.queryType === QueryType.Search
+  const isTableMode = query.queryType === QueryType.Table
+  const isLastValueMode = query.queryType === QueryType.LastValue
+  const isAlarmsMode = query.queryType === QueryType.Alarms
+  const isLogsMode = query.queryType === QueryType.Logs
 
   /* ===================================================== HOOKS ============================================================*/
   const [group, setGroup] = useState<string>(query.group || '')
@@ -41,10 +84,13 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
   //@ts-ignore
   const [channel, setChannel] = useState<string>(query.channel || '')
   const [channelQuery, setChannelQuery] = useState<string[]>(query.channelArray || [])
+  // Channel name -> PRTG channel id, channels are stored by id so renaming does not break panels
+  const channelIdsByName = useRef<Record<string, string>>({})
   const [sensorId, setSensorId] = useState<string>(query.sensorId || '')
   const [manualMethod, setManualMethod] = useState<string>(query.manualMethod || '');
   const [manualObjectId, setManualObjectId] = useState<string>(query.manualObjectId || '');
   const [streamIntervalValue, setStreamIntervalValue] = useState<string>(String(query.streamInterval || 2500));
+  const [tableFilterText, setTableFilterText] = useState<string>(formatFilters(query.tableFilters));
 
   const [lists, setLists] = useState({
     groups: [] as Array<ComboboxOption<string>>,
@@ -209,15 +255,15 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
           return;
         }
 
-        if (response.values && Array.isArray(response.values) && response.values.length > 0) {
-          const channelData = response.values[0] || {};
-
-          const channelOptions = Object.entries(channelData)
-            .filter(([key]) => key !== 'datetime')
-            .map(([key]) => ({
-              label: key,
-              value: key,
-            }));
+        if (response.channels && Array.isArray(response.channels) && response.channels.length > 0) {
+          const channelOptions = response.channels.map((item) => {
+            channelIdsByName.current[item.name] = String(item.objid);
+            return {
+              label: item.name,
+              value: item.name,
+              description: item.unit || undefined,
+            };
+          });
 
           setLists((prev) => ({
             ...prev,
@@ -279,6 +325,7 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
     setManualMethod((prev) => query.manualMethod ?? prev);
     setManualObjectId((prev) => query.manualObjectId ?? prev);
     setStreamIntervalValue(String(query.streamInterval || 2500));
+    setTableFilterText(formatFilters(query.tableFilters));
     // Add this line to restore channel selections
     setChannelQuery((prev) => query.channelArray || prev || []);
   }, [query]);
@@ -368,25 +415,20 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
       }
 
       // Check if response has the expected structure
-      if (typeof response === 'object' && 'values' in response) {
-        const values = response.values;
-        if (!Array.isArray(values) || values.length === 0) {
-          console.warn('No channel values found in response');
+      if (typeof response === 'object' && Array.isArray(response.channels)) {
+        if (response.channels.length === 0) {
+          console.warn('No channels found in response');
           return [];
         }
 
-        const channelData = values[0];
-        if (typeof channelData !== 'object') {
-          console.warn('Invalid channel data format');
-          return [];
-        }
-
-        return Object.keys(channelData)
-          .filter(key => key !== 'datetime')
-          .map(key => ({
-            label: key,
-            value: key,
-          }));
+        return response.channels.map((item) => {
+          channelIdsByName.current[item.name] = String(item.objid);
+          return {
+            label: item.name,
+            value: item.name,
+            description: item.unit || undefined,
+          };
+        });
       }
 
       console.warn('Unexpected response format:', response);
@@ -467,12 +509,18 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
     // Update local state
     setChannelQuery(selectedChannels);
 
+    // The backend prefers channel ids, they are only sent if every selected channel resolved.
+    // Otherwise a partially loaded channel list would drop channels from the query.
+    const resolvedIds = selectedChannels.map(channel => channelIdsByName.current[channel]);
+    const channelIds = resolvedIds.every((id): id is string => id !== undefined) ? resolvedIds as string[] : [];
+
     // CRITICAL: Update query to include ALL selected channels in a SINGLE query
     // This prevents Grafana from creating multiple queries (refId A, B, C...)
     const updatedQuery = {
       ...query,
       channel: selectedChannels[0] || '', // First channel for backward compatibility
       channelArray: selectedChannels, // ALL selected channels in one array
+      channelIds,
       // Generate series names for each channel
       seriesNames: selectedChannels.map(channel =>
         `${query.sensor || 'Sensor'} - ${channel}`
@@ -507,7 +555,50 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
     const updatedQuery = { ...query, includeSensorName: event.currentTarget.checked };
     onChange(updatedQuery);
     runQueryIfChanged();
-  }  /* ==================================================  ON MANUAL OBJECT ID CHANGE ==================================================  */
+  }
+  /* ==================================================  ON SEARCH OPTIONS CHANGE ==================================================  */
+  const onSearchFilterChange = (key: 'groupFilter' | 'deviceFilter' | 'sensorFilter' | 'sensorType') =>
+    (event: ChangeEvent<HTMLInputElement>) => {
+      onChange({ ...query, [key]: event.currentTarget.value });
+    }
+
+  const onSearchTagsChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const tags = event.currentTarget.value
+      .split(',')
+      .map((tag) => tag.trim())
+      .filter((tag) => tag !== '');
+    onChange({ ...query, tags });
+  }
+
+  const onMaxSeriesChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const value = parseInt(event.currentTarget.value, 10);
+    onChange({ ...query, maxSeries: isNaN(value) ? undefined : value });
+  }
+
+  const onLimitChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const value = parseInt(event.currentTarget.value, 10);
+    onChange({ ...query, limit: isNaN(value) ? undefined : value });
+  }
+
+  /* ==================================================  ON TABLE OPTIONS CHANGE ==================================================  */
+  const onTableColumnsChange = (event: ChangeEvent<HTMLInputElement>) => {
+    onChange({ ...query, tableColumns: splitList(event.currentTarget.value) });
+  }
+
+  // Filters are parsed when the field is left, incomplete filters would be dropped while typing
+  const onTableFiltersBlur = () => {
+    const tableFilters = parseFilters(tableFilterText);
+    setTableFilterText(formatFilters(tableFilters));
+    onChange({ ...query, tableFilters });
+    runQueryIfChanged();
+  }
+
+  /* ==================================================  ON LOG TYPES CHANGE ==================================================  */
+  const onLogTypesChange = (event: ChangeEvent<HTMLInputElement>) => {
+    onChange({ ...query, logTypes: splitList(event.currentTarget.value) });
+  }
+
+  /* ==================================================  ON MANUAL OBJECT ID CHANGE ==================================================  */
   const onManualObjectIdChange = (event: ChangeEvent<HTMLInputElement>) => {
     const value = event.currentTarget.value;
     setManualObjectId(value);
@@ -669,7 +760,7 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
 
 
       {/* Show display name options for both Metrics and Streaming */}
-      {(isMetricsMode || query.isStreaming || isRawMode || isTextMode) && (
+      {(isMetricsMode || isSearchMode || query.isStreaming || isRawMode || isTextMode) && (
         <FieldSet label="Display Options">
           <Stack direction="row" gap={1}>
             <InlineField label="Include Group" labelWidth={16}>
@@ -697,6 +788,277 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource }: Props)
         </FieldSet>
       )}
       
+      {/* Sensor search: the channel selection above applies to every matching sensor.
+          Last value queries use the same filters, without channels they list one row per sensor. */}
+      {(isSearchMode || isLastValueMode) && (
+        <FieldSet label={isLastValueMode ? 'Last Value' : 'Sensor Search'}>
+          <Stack direction="row" gap={2}>
+            <Stack direction="column" gap={1}>
+              <InlineField label="Tags" labelWidth={16} tooltip="Comma separated, all tags must match">
+                <Input
+                  id='query-editor-search-tags'
+                  value={(query.tags || []).join(', ')}
+                  onChange={onSearchTagsChange}
+                  onBlur={runQueryIfChanged}
+                  placeholder="e.g. core, switch"
+                  width={32}
+                />
+              </InlineField>
+              <InlineField label="Sensor Type" labelWidth={16} tooltip="Wildcard (*, ?) or /regex/">
+                <Input
+                  id='query-editor-search-sensor-type'
+                  value={query.sensorType || ''}
+                  onChange={onSearchFilterChange('sensorType')}
+                  onBlur={runQueryIfChanged}
+                  placeholder="e.g. snmptraffic"
+                  width={32}
+                />
+              </InlineField>
+              <InlineField label="Max Series" labelWidth={16} tooltip="Maximum number of returned series">
+                <Input
+                  id='query-editor-search-max-series'
+                  type="number"
+                  value={query.maxSeries ?? ''}
+                  onChange={onMaxSeriesChange}
+                  onBlur={runQueryIfChanged}
+                  placeholder="50"
+                  min={1}
+                  width={32}
+                />
+              </InlineField>
+              {isLastValueMode && (
+                <InlineField label="Limit" labelWidth={16} tooltip="Maximum number of sensors without channel selection">
+                  <Input
+                    id='query-editor-lastvalue-limit'
+                    type="number"
+                    value={query.limit ?? ''}
+                    onChange={onLimitChange}
+                    onBlur={runQueryIfChanged}
+                    placeholder="All sensors"
+                    min={1}
+                    width={32}
+                  />
+                </InlineField>
+              )}
+            </Stack>
+            <Stack direction="column" gap={1}>
+              <InlineField label="Group Filter" labelWidth={16} tooltip="Wildcard (*, ?) or /regex/">
+                <Input
+                  id='query-editor-search-group'
+                  value={query.groupFilter || ''}
+                  onChange={onSearchFilterChange('groupFilter')}
+                  onBlur={runQueryIfChanged}
+                  placeholder="e.g. Berlin*"
+                  width={32}
+                />
+              </InlineField>
+              <InlineField label="Device Filter" labelWidth={16} tooltip="Wildcard (*, ?) or /regex/">
+                <Input
+                  id='query-editor-search-device'
+                  value={query.deviceFilter || ''}
+                  onChange={onSearchFilterChange('deviceFilter')}
+                  onBlur={runQueryIfChanged}
+                  placeholder="e.g. sw-??"
+                  width={32}
+                />
+              </InlineField>
+              <InlineField label="Sensor Filter" labelWidth={16} tooltip="Wildcard (*, ?) or /regex/">
+                <Input
+                  id='query-editor-search-sensor'
+                  value={query.sensorFilter || ''}
+                  onChange={onSearchFilterChange('sensorFilter')}
+                  onBlur={runQueryIfChanged}
+                  placeholder="e.g. /^Traffic/"
+                  width={32}
+                />
+              </InlineField>
+            </Stack>
+          </Stack>
+        </FieldSet>
+      )}
+
+      {/* Table query: PRTG object list with the selected columns */}
+      {isTableMode && (
+        <FieldSet label="Table">
+          <Stack direction="row" gap={2}>
+            <Stack direction="column" gap={1}>
+              <InlineField label="Content" labelWidth={16} tooltip="Object type of the table">
+                <Combobox
+                  id='query-editor-table-content'
+                  options={tableContentOptions}
+                  value={query.tableContent}
+                  onChange={(option) => {
+                    if (option?.value) {
+                      onChange({ ...query, tableContent: option.value as MyQuery['tableContent'] });
+                      runQueryIfChanged();
+                    }
+                  }}
+                  width={32}
+                  placeholder="Select content"
+                />
+              </InlineField>
+              <InlineField label="Columns" labelWidth={16} tooltip="Comma separated PRTG columns, <column>_raw for the raw value">
+                <Input
+                  id='query-editor-table-columns'
+                  value={(query.tableColumns || []).join(', ')}
+                  onChange={onTableColumnsChange}
+                  onBlur={runQueryIfChanged}
+                  placeholder="Default columns of the content"
+                  width={32}
+                />
+              </InlineField>
+              <InlineField label="Filters" labelWidth={16} tooltip="Semicolon separated, operators =, !=, =~, !~, <, >">
+                <Input
+                  id='query-editor-table-filters'
+                  value={tableFilterText}
+                  onChange={(e: ChangeEvent<HTMLInputElement>) => setTableFilterText(e.currentTarget.value)}
+                  onBlur={onTableFiltersBlur}
+                  placeholder="e.g. status = Down; type =~ ping"
+                  width={32}
+                />
+              </InlineField>
+            </Stack>
+            <Stack direction="column" gap={1}>
+              <InlineField label="Sort By" labelWidth={16} tooltip="One of the selected columns">
+                <Input
+                  id='query-editor-table-sort'
+                  value={query.tableSort || ''}
+                  onChange={(e: ChangeEvent<HTMLInputElement>) => onChange({ ...query, tableSort: e.currentTarget.value.trim() })}
+                  onBlur={runQueryIfChanged}
+                  placeholder="e.g. lastvalue_raw"
+                  width={32}
+                />
+              </InlineField>
+              <InlineField label="Descending" labelWidth={16}>
+                <InlineSwitch
+                  id={`query-editor-table-sort-desc-${query.refId}`}
+                  value={query.tableSortDesc || false}
+                  onChange={(e: ChangeEvent<HTMLInputElement>) => {
+                    onChange({ ...query, tableSortDesc: e.currentTarget.checked });
+                    runQueryIfChanged();
+                  }}
+                />
+              </InlineField>
+              <InlineField label="Limit" labelWidth={16} tooltip="Maximum number of rows">
+                <Input
+                  id='query-editor-table-limit'
+                  type="number"
+                  value={query.limit ?? ''}
+                  onChange={onLimitChange}
+                  onBlur={runQueryIfChanged}
+                  placeholder="All rows"
+                  min={1}
+                  width={32}
+                />
+              </InlineField>
+            </Stack>
+          </Stack>
+        </FieldSet>
+      )}
+
+      {/* Alarms query: sensors in an alarm state, the group selection above restricts the list */}
+      {isAlarmsMode && (
+        <FieldSet label="Alarms">
+          <Stack direction="row" gap={2}>
+            <Stack direction="column" gap={1}>
+              <InlineField label="States" labelWidth={16} tooltip="Alarm states to list, all if empty">
+                <MultiSelect
+                  id='query-editor-alarm-states'
+                  options={alarmStateOptions}
+                  value={query.alarmStates || []}
+                  onChange={(values: Array<SelectableValue<string>>) => {
+                    onChange({ ...query, alarmStates: values.map((v) => v.value) as MyQuery['alarmStates'] });
+                    runQueryIfChanged();
+                  }}
+                  width={32}
+                  placeholder="All states"
+                />
+              </InlineField>
+              <InlineField label="Min. Priority" labelWidth={16} tooltip="Minimum sensor priority (1-5)">
+                <Input
+                  id='query-editor-alarm-min-priority'
+                  type="number"
+                  value={query.alarmMinPriority ?? ''}
+                  onChange={(e: ChangeEvent<HTMLInputElement>) => {
+                    const value = parseInt(e.currentTarget.value, 10);
+                    onChange({ ...query, alarmMinPriority: isNaN(value) ? undefined : value });
+                  }}
+                  onBlur={runQueryIfChanged}
+                  placeholder="1"
+                  min={1}
+                  max={5}
+                  width={32}
+                />
+              </InlineField>
+            </Stack>
+            <Stack direction="column" gap={1}>
+              <InlineField label="Group Filter" labelWidth={16} tooltip="Wildcard (*, ?) or /regex/">
+                <Input
+                  id='query-editor-alarm-group'
+                  value={query.groupFilter || ''}
+                  onChange={onSearchFilterChange('groupFilter')}
+                  onBlur={runQueryIfChanged}
+                  placeholder="e.g. Berlin*"
+                  width={32}
+                />
+              </InlineField>
+              <InlineField label="Tags" labelWidth={16} tooltip="Comma separated, all tags must match">
+                <Input
+                  id='query-editor-alarm-tags'
+                  value={(query.tags || []).join(', ')}
+                  onChange={onSearchTagsChange}
+                  onBlur={runQueryIfChanged}
+                  placeholder="e.g. core, switch"
+                  width={32}
+                />
+              </InlineField>
+              <InlineField label="Limit" labelWidth={16} tooltip="Maximum number of alarms">
+                <Input
+                  id='query-editor-alarm-limit'
+                  type="number"
+                  value={query.limit ?? ''}
+                  onChange={onLimitChange}
+                  onBlur={runQueryIfChanged}
+                  placeholder="All alarms"
+                  min={1}
+                  width={32}
+                />
+              </InlineField>
+            </Stack>
+          </Stack>
+        </FieldSet>
+      )}
+
+      {/* Logs query: PRTG log of the selected sensors, devices or groups */}
+      {isLogsMode && (
+        <FieldSet label="Logs">
+          <Stack direction="row" gap={2}>
+            <InlineField label="Log Types" labelWidth={16} tooltip="Comma separated message types, all if empty">
+              <Input
+                id='query-editor-log-types'
+                value={(query.logTypes || []).join(', ')}
+                onChange={onLogTypesChange}
+                onBlur={runQueryIfChanged}
+                placeholder="e.g. Down, Up, Paused"
+                width={32}
+              />
+            </InlineField>
+            <InlineField label="Limit" labelWidth={16} tooltip="Maximum number of log entries, shared by the selected objects">
+              <Input
+                id='query-editor-log-limit'
+                type="number"
+                value={query.limit ?? ''}
+                onChange={onLimitChange}
+                onBlur={runQueryIfChanged}
+                placeholder="1000"
+                min={1}
+                width={32}
+              />
+            </InlineField>
+          </Stack>
+        </FieldSet>
+      )}
+
       {/* Options for Text and Raw modes */}
       {(isTextMode || isRawMode) && (
         <FieldSet label="Options">
@@ -83,6 +83,19 @@ describe('DataSource', () => {
         expect(result.channel).toBe('replaced-channel');
     });
 
+    it('should interpolate multi-value object ids as comma separated list', () => {
+        const query = createMockQuery({ sensorId: '$sensor', sensorIds: ['$sensors'] });
+        mockTemplateSrv.replace.mockImplementation((value: string, _vars: unknown, format?: string) =>
+            format === 'csv' ? '1001,1002' : value
+        );
+
+        const result = dataSource.applyTemplateVariables(query, {});
+
+        expect(mockTemplateSrv.replace).toHaveBeenCalledWith('$sensor', {}, 'csv');
+        expect(result.sensorId).toBe('1001,1002');
+        expect(result.sensorIds).toEqual(['1001', '1002']);
+    });
+
     it('should filter queries by channel', () => {
         expect(dataSource.filterQuery(createMockQuery({ channel: 'test' }))).toBe(true);
         expect(dataSource.filterQuery(createMockQuery({ channel: '' }))).toBe(false);
@@ -1,5 +1,7 @@
 import { 
+  AdHocVariableFilter,
   DataSourceInstanceSettings, 
+  MetricFindValue,
   ScopedVars, 
   AnnotationEvent,
   DataFrame,
@@ -22,6 +24,7 @@ import {
   PRTGSensorListResponse,
   PRTGChannelListResponse,
   QueryType,
+  VariableQuery,
 } from './types'
 
 export class DataSource extends DataSourceWithBackend<MyQuery, MyDataSourceOptions> {
@@ -30,16 +33,58 @@ export class DataSource extends DataSourceWithBackend<MyQuery, MyDataSourceOptio
     super(instanceSettings);
   }
 
-  applyTemplateVariables(query: MyQuery, scopedVars: ScopedVars) {
-    const replaced = getTemplateSrv().replace(query.channel, scopedVars);
+  applyTemplateVariables(query: MyQuery, scopedVars: ScopedVars, filters?: AdHocVariableFilter[]) {
+    const templateSrv = getTemplateSrv();
+    const replaced = templateSrv.replace(query.channel, scopedVars);
+    // Multi-value variables expand to "1,2,3", the backend returns one series per object
+    const replaceIds = (value?: string) => (value ? templateSrv.replace(value, scopedVars, 'csv') : value);
+    const replaceIdList = (values?: string[]) =>
+      values?.flatMap((value) => (replaceIds(value) || '').split(',')).filter(Boolean);
     return {
       ...query,
       channel: replaced,
+      sensorId: replaceIds(query.sensorId),
+      deviceId: replaceIds(query.deviceId),
+      groupId: replaceIds(query.groupId),
+      sensorIds: replaceIdList(query.sensorIds),
+      deviceIds: replaceIdList(query.deviceIds),
+      groupIds: replaceIdList(query.groupIds),
+      adhocFilters: filters?.length
+        ? filters.map(({ key, operator, value }) => ({ key, operator, value: templateSrv.replace(value, scopedVars) }))
+        : query.adhocFilters,
     }
   }
 
+  // Keys and values of ad-hoc filters, applied by the backend to sensor lists
+  async getTagKeys(): Promise<MetricFindValue[]> {
+    return this.getResource('tag-keys');
+  }
+
+  async getTagValues(options: { key: string }): Promise<MetricFindValue[]> {
+    return this.getResource('tag-values', { key: options.key });
+  }
+
+  // Skips queries which are not complete yet, what is required depends on the query type
   filterQuery(query: MyQuery): boolean {
-    return !!query.channel
+    const hasChannel = !!(query.channel || query.channelArray?.length || query.channelIds?.length);
+    switch (query.queryType || QueryType.Metrics) {
+      case QueryType.Metrics:
+        return hasChannel || !!query.expression;
+      case QueryType.Search:
+        return hasChannel;
+      case QueryType.Raw:
+      case QueryType.Text:
+        return !!(query.property && query.filterProperty);
+      case QueryType.Manual:
+        return !!query.manualMethod;
+      case QueryType.Variables:
+        return !!query.variable?.kind;
+      case QueryType.Table:
+        return !!query.tableContent;
+      default:
+        // Last value, alarms and logs fall back to all objects
+        return true;
+    }
   }
 
   async getGroups(): Promise<PRTGGroupListResponse> {
@@ -67,6 +112,39 @@ export class DataSource extends DataSourceWithBackend<MyQuery, MyDataSourceOptio
     return this.getResource(`channels/${encodeURIComponent(sensorId)}`);
   }
 
+  /**
+   * Options of a dashboard variable. The query is either a VariableQuery or a string like
+   * "devices?group=$group&status=up" with the parameters of the variables resource.
+   * Chained variables reference their parent variable in parentId, group or device.
+   */
+  async metricFindQuery(query: VariableQuery | string, options?: { scopedVars?: ScopedVars }): Promise<MetricFindValue[]> {
+    const params: Record<string, string> = {};
+    if (typeof query === 'string') {
+      const [kind, search = ''] = query.trim().split('?', 2);
+      params.kind = kind;
+      new URLSearchParams(search).forEach((value, key) => {
+        params[key] = value;
+      });
+    } else {
+      const { kind, parentId, group, device, tags, status, sensorType, regex, valueField } = query;
+      Object.assign(params, { kind, parentId, group, device, status, type: sensorType, regex, valueField });
+      if (tags?.length) {
+        params.tags = tags.join(',');
+      }
+    }
+
+    const templateSrv = getTemplateSrv();
+    const interpolated: Record<string, string> = {};
+    Object.entries(params).forEach(([key, value]) => {
+      if (value) {
+        interpolated[key] = templateSrv.replace(value, options?.scopedVars);
+      }
+    });
+
+    const result: Array<{ text: string; value: string }> = await this.getResource('variables', interpolated);
+    return result.map(({ text, value }) => ({ text, value }));
+  }
+
   annotations = {
     QueryEditor: undefined,
     processEvents: (anno: any, data: DataFrame[]): Observable<AnnotationEvent[]> => {
@@ -3,9 +3,15 @@ import { DataQuery } from '@grafana/schema';
 
 export enum QueryType {
   Metrics = 'metrics',
+  Search = 'search',
   Raw = 'raw',
   Text = 'text',
   Manual = 'manual',
+  Variables = 'variables',
+  Table = 'table',
+  LastValue = 'lastvalue',
+  Alarms = 'alarms',
+  Logs = 'logs',
 }
 
 export interface MyQuery extends DataQuery {
@@ -18,6 +24,7 @@ export interface MyQuery extends DataQuery {
   sensorId: string;
   channel: string;
   channelArray: string[];
+  channelIds?: string[]; // PRTG channel ids, preferred over channel captions
   manualMethod?: string;
   manualObjectId?: string;
   property?: string;
@@ -25,6 +32,37 @@ export interface MyQuery extends DataQuery {
   includeGroupName?: boolean;
   includeDeviceName?: boolean;
   includeSensorName?: boolean;
+  disableChannelConfig?: boolean; // Skip unit, min/max and thresholds from PRTG channel settings
+  nullValueMode?: 'null' | 'previous' | 'zero'; // How missing channel values are treated
+  timestampAlignment?: 'start' | 'midpoint' | 'end'; // Timestamp of averaged rows, PRTG uses the interval end
+  includeIntervalBounds?: boolean; // Add interval start/end time fields
+  timezone?: string; // IANA timezone of the PRTG timestamps, overrides the datasource timezone
+  downsample?: 'min' | 'max' | 'mean' | 'lttb'; // Backend downsampling to maxDataPoints
+  transforms?: ChannelTransform[]; // Per-channel transforms, applied in order in the backend
+  expression?: string; // Arithmetic expression over expressionSeries, e.g. "in + out"
+  expressionSeries?: ExpressionSeries[]; // Named channels used in the expression
+  expressionName?: string; // Display name of the expression result
+  expressionUnit?: string; // Unit of the expression result
+  tags?: string[]; // Sensor search: all tags must match
+  groupFilter?: string; // Sensor search: wildcard ("*", "?") or /regex/ on the group name
+  deviceFilter?: string; // Sensor search: pattern on the device name
+  sensorFilter?: string; // Sensor search: pattern on the sensor name
+  sensorType?: string; // Sensor search: pattern on the sensor type
+  maxSeries?: number; // Sensor search: maximum number of series
+  variable?: VariableQuery; // Object list of a template variable query
+  sensorIds?: string[]; // Several sensors, one series per sensor and channel
+  deviceIds?: string[]; // All sensors of these devices if no sensor is selected
+  groupIds?: string[]; // All sensors of these groups if no sensor or device is selected
+  adhocFilters?: AdhocFilter[]; // Dashboard ad-hoc filters, set when the query is sent
+  tableContent?: 'probes' | 'groups' | 'devices' | 'sensors' | 'channels'; // Table query: object type
+  tableColumns?: string[]; // Table query: PRTG columns, "<column>_raw" for the raw value
+  tableFilters?: AdhocFilter[]; // Table query: filters on any column
+  tableSort?: string; // Table query: selected column to sort by
+  tableSortDesc?: boolean; // Table query: sort descending
+  limit?: number; // Table, last value, alarms and logs query: maximum number of rows
+  alarmStates?: Array<'down' | 'partial' | 'acknowledged' | 'warning' | 'unusual'>; // Alarms query: states, default all
+  alarmMinPriority?: number; // Alarms query: minimum sensor priority (1-5)
+  logTypes?: string[]; // Logs query: message types, e.g. "Down", "Up", "Paused"
   refId: string;
 
   // Add the streaming config
@@ -42,6 +80,43 @@ export interface MyQuery extends DataQuery {
   updateMode?: 'full' | 'append';
 }
 
+export interface ChannelTransform {
+  type: 'rate' | 'delta' | 'multiply' | 'divide' | 'convertUnit' | 'movingAverage' | 'ewma';
+  channel?: string; // Channel id or name, all channels if empty
+  value?: number; // Factor of multiply/divide
+  window?: number; // Number of values of movingAverage
+  alpha?: number; // Smoothing factor of ewma (0-1]
+  from?: string; // Source unit of convertUnit, defaults to the channel unit
+  to?: string; // Target unit of convertUnit
+  nonNegative?: boolean; // Drop negative rate/delta values (counter resets)
+  unit?: string; // Unit of the result
+}
+
+export interface AdhocFilter {
+  key: string; // probe, group, device, sensor, status, tags, type or priority
+  operator: string; // =, !=, =~, !~, <, >
+  value: string;
+}
+
+export interface VariableQuery {
+  kind: 'groups' | 'devices' | 'sensors' | 'channels';
+  parentId?: string; // Object id the list is restricted to, required for channels (sensor id)
+  group?: string; // Exact group name, e.g. $group
+  device?: string; // Exact device name
+  tags?: string[]; // All tags must be set on the object
+  status?: string; // up, warning, down, paused, unusual, acknowledged, partial or a PRTG status code
+  sensorType?: string; // Wildcard ("*", "?") or /regex/ on the sensor type
+  regex?: string; // Regular expression on the object name
+  valueField?: 'id' | 'name'; // Object ids (default) or names as values
+}
+
+export interface ExpressionSeries {
+  name: string; // Identifier used in the expression
+  sensorId?: string; // Defaults to the sensor of the query
+  channelId?: string;
+  channel?: string;
+}
+
 // Organize streaming options better for clarity
 export interface StreamingConfig {
   isStreaming?: boolean;      // Whether streaming is enabled
@@ -100,6 +175,8 @@ export interface MyDataSourceOptions extends DataSourceJsonData {
   path?: string;
   cacheTime?: number;
   timeZone?: string;
+  autoTimezone?: boolean; // Use the UTC offset detected from the PRTG server clock
+  decimalSeparator?: '' | ',' | '.'; // Decimal separator of formatted PRTG values, empty detects it per value
 }
 
 export interface MySecureJsonData {
@@ -183,12 +260,20 @@ export interface PRTGSensorResponse {
 export interface PRTGChannelListResponse {
   prtgversion: string;
   treesize: number;
-  values: PRTGItemChannel[];
+  channels: PRTGItemChannel[];
 }
 
 export interface PRTGItemChannel {
-  [key: string]: number | string;
-  datetime: string;
+  objid: number;
+  name: string;
+  lastvalue: string;
+  lastvalue_raw: number | null;
+  unit: string;
+  limitmaxerror: number | null;
+  limitmaxwarning: number | null;
+  limitminerror: number | null;
+  limitminwarning: number | null;
+  valuelookup: string;
 }
 
 